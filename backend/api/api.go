@@ -1,6 +1,8 @@
 package api
 
 import (
+	"expvar"
+
 	"backend/middleware"
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,9 @@ func RegisterRoutes(router *gin.Engine) {
 	apiGroup.POST("/auth/login", Login)
 	apiGroup.POST("/auth/register", Register)
 
+	// 语言列表 - 无需认证
+	apiGroup.GET("/languages", GetLanguages)
+
 	// 需要认证的路由
 	authRequired := apiGroup.Group("/")
 	authRequired.Use(middleware.JWT())
@@ -28,15 +33,45 @@ func RegisterRoutes(router *gin.Engine) {
 		authRequired.POST("/problems", CreateProblem)
 		authRequired.PUT("/problems/:id", UpdateProblem)
 		authRequired.DELETE("/problems/:id", DeleteProblem)
-		authRequired.POST("/problems/:id/testcases", addTestCase)
+
+		// 测试用例管理
+		authRequired.GET("/problems/:id/testcases", middleware.AdminRequired(), GetTestCases)
+		authRequired.POST("/problems/:id/testcases", middleware.AdminRequired(), CreateTestCase)
+		authRequired.POST("/problems/:id/testcases/reorder", middleware.AdminRequired(), ReorderTestCases)
+		authRequired.POST("/problems/:id/testcases/upload", middleware.AdminRequired(), UploadTestData)
+		authRequired.GET("/problems/:id/testdata/versions", middleware.AdminRequired(), GetTestDataVersions)
+		authRequired.PUT("/problems/:id/testcases/:case_id", middleware.AdminRequired(), UpdateTestCase)
+		authRequired.DELETE("/problems/:id/testcases/:case_id", middleware.AdminRequired(), DeleteTestCase)
+		authRequired.GET("/problems/:id/subtasks", middleware.AdminRequired(), GetSubtasks)
+		authRequired.POST("/problems/:id/subtasks", middleware.AdminRequired(), CreateSubtask)
+		authRequired.PUT("/problems/:id/subtasks/:subtask_id", middleware.AdminRequired(), UpdateSubtask)
+		authRequired.DELETE("/problems/:id/subtasks/:subtask_id", middleware.AdminRequired(), DeleteSubtask)
+		authRequired.GET("/problems/:id/language-limits", middleware.AdminRequired(), GetLanguageLimits)
+		authRequired.PUT("/problems/:id/language-limits", middleware.AdminRequired(), SetLanguageLimits)
 
 		// 提交相关
 		authRequired.POST("/submit", SubmitHandler)
+		authRequired.POST("/run", RunCode)
+		authRequired.GET("/run/:id", GetRun)
 		authRequired.GET("/submissions", GetSubmissions)
 		authRequired.GET("/submissions/:id", GetSubmission)
 		authRequired.GET("/submissions/:id/result", GetSubmissionResult)
+		authRequired.GET("/submissions/:id/compile-log", GetCompileLog)
+		authRequired.GET("/submissions/:id/stream", StreamSubmission)
 		authRequired.GET("/:id/submit-state", GetUserSubmitState)
 
+		// 比赛相关
+		authRequired.GET("/contests", GetContests)
+		authRequired.GET("/contests/:id", GetContest)
+		authRequired.POST("/contests", middleware.AdminRequired(), CreateContest)
+		authRequired.PUT("/contests/:id", middleware.AdminRequired(), UpdateContest)
+		authRequired.DELETE("/contests/:id", middleware.AdminRequired(), DeleteContest)
+		authRequired.POST("/contests/:id/register", RegisterContest)
+		authRequired.DELETE("/contests/:id/register", UnregisterContest)
+		authRequired.GET("/contests/:id/scoreboard", GetScoreboard)
+		authRequired.POST("/contests/:id/unfreeze", middleware.AdminRequired(), UnfreezeContest)
+		authRequired.GET("/contests/:id/event-feed", middleware.AdminRequired(), GetContestEventFeed)
+
 		// 仪表板相关
 		authRequired.GET("/dashboard/stats", GetDashboardStats)
 		authRequired.GET("/dashboard/activities", GetRecentActivities)
@@ -50,6 +85,23 @@ func RegisterRoutes(router *gin.Engine) {
 			adminRequired.GET("/users/:id", GetUser)
 			adminRequired.PUT("/users/:id", UpdateUser)
 			adminRequired.DELETE("/users/:id", DeleteUser)
+
+			// 判题机管理
+			adminRequired.GET("/judges", GetJudges)
+			adminRequired.POST("/judges/:id/drain", DrainJudge)
+			adminRequired.POST("/judges/:id/disable", DisableJudge)
+			adminRequired.POST("/judges/:id/enable", EnableJudge)
+			adminRequired.DELETE("/judges/:id", DeleteJudge)
+
+			// 重新评测
+			adminRequired.POST("/submissions/:id/rejudge", RejudgeSubmission)
+			adminRequired.POST("/problems/:id/rejudge", RejudgeProblem)
+			adminRequired.POST("/users/:id/rejudge", RejudgeUser)
+			adminRequired.GET("/rejudges", GetRejudges)
+			adminRequired.GET("/rejudges/:id", GetRejudge)
+
+			// 运行指标（expvar）
+			adminRequired.GET("/metrics", gin.WrapH(expvar.Handler()))
 		}
 	}
 }
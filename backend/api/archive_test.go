@@ -0,0 +1,205 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"backend/storage"
+)
+
+// buildZip 在内存中按顺序构造压缩包，contents 中没有的文件内容为空，以 / 结尾的为目录
+func buildZip(t *testing.T, files []string, contents map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := io.WriteString(w, contents[name]); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	return zr
+}
+
+func TestParseTestDataArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    []string // 按顺序配对的用例名称
+		config  string   // 期望的配置文件路径
+		wantErr string
+	}{
+		{
+			name:  "pairs in and out",
+			files: []string{"1.in", "1.out", "2.in", "2.ans"},
+			want:  []string{"1", "2"},
+		},
+		{
+			name:  "natural order",
+			files: []string{"10.in", "10.out", "2.in", "2.out", "b.in", "b.out", "a.in", "a.out"},
+			want:  []string{"2", "10", "a", "b"},
+		},
+		{
+			name:   "nested directories",
+			files:  []string{"data/", "data/1.in", "data/1.out", "data/sub/2.in", "data/sub/2.out", "data/config.yaml"},
+			want:   []string{"1", "2"},
+			config: "data/config.yaml",
+		},
+		{
+			name:  "skips hidden and macos files",
+			files: []string{"1.in", "1.out", ".DS_Store", "__MACOSX/._1.in"},
+			want:  []string{"1"},
+		},
+		{
+			name:    "missing output",
+			files:   []string{"1.in", "1.out", "2.in"},
+			wantErr: "test case 2 must have both an input and an output file",
+		},
+		{
+			name:    "missing input",
+			files:   []string{"1.out"},
+			wantErr: "test case 1 must have both an input and an output file",
+		},
+		{
+			name:    "duplicate across directories",
+			files:   []string{"a/1.in", "b/1.in", "1.out"},
+			wantErr: "duplicate file for test case 1",
+		},
+		{
+			name:    "out and ans for the same case",
+			files:   []string{"1.in", "1.out", "1.ans"},
+			wantErr: "duplicate file for test case 1",
+		},
+		{
+			name:    "unexpected file",
+			files:   []string{"1.in", "1.out", "readme.txt"},
+			wantErr: "unexpected file readme.txt",
+		},
+		{
+			name:    "two configs",
+			files:   []string{"1.in", "1.out", "config.yaml", "x/config.yml"},
+			wantErr: "archive contains more than one config.yaml",
+		},
+		{
+			name:    "empty archive",
+			files:   []string{"data/"},
+			wantErr: "archive contains no test cases",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, configFile, err := parseTestDataArchive(buildZip(t, tt.files, nil))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for _, pair := range pairs {
+				if pair.input == nil || pair.output == nil {
+					t.Fatalf("case %s is not paired", pair.name)
+				}
+				names = append(names, pair.name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("cases = %v, want %v", names, tt.want)
+			}
+
+			gotConfig := ""
+			if configFile != nil {
+				gotConfig = configFile.Name
+			}
+			if gotConfig != tt.config {
+				t.Errorf("config = %q, want %q", gotConfig, tt.config)
+			}
+		})
+	}
+}
+
+func TestBudgetReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		budget  int64
+		wantErr error
+	}{
+		{name: "within budget", size: 100, budget: 1000},
+		{name: "exactly the budget", size: 1000, budget: 1000},
+		{name: "over budget", size: 1001, budget: 1000, wantErr: errDataTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining := tt.budget
+			r := &budgetReader{r: strings.NewReader(strings.Repeat("x", tt.size)), remaining: &remaining}
+			_, err := io.Copy(io.Discard, r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && remaining != tt.budget-int64(tt.size) {
+				t.Errorf("remaining = %d, want %d", remaining, tt.budget-int64(tt.size))
+			}
+		})
+	}
+}
+
+// TestStoreTestDataBudget 高压缩率的大文件解压时超过总预算即停止，预算在多个文件间累计
+func TestStoreTestDataBudget(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	old := testDataStore
+	testDataStore = store
+	defer func() { testDataStore = old }()
+
+	bomb := strings.Repeat("\x00", 8<<20)
+	zr := buildZip(t, []string{"1.in", "1.out", "2.in"}, map[string]string{
+		"1.in":  "1 2\n",
+		"1.out": "3\n",
+		"2.in":  bomb,
+	})
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files["2.in"].CompressedSize64 >= 1<<20 {
+		t.Fatalf("compressed size %d, want a highly compressible file", files["2.in"].CompressedSize64)
+	}
+
+	budget := int64(1 << 20)
+	content, file, err := storeTestData(files["1.in"], "p/1.in", &budget)
+	if err != nil || content != "1 2\n" || file != "" {
+		t.Fatalf("small file = (%q, %q, %v), want inline content", content, file, err)
+	}
+	if _, _, err := storeTestData(files["1.out"], "p/1.out", &budget); err != nil {
+		t.Fatalf("small file: %v", err)
+	}
+	if budget != 1<<20-6 {
+		t.Errorf("budget = %d, want %d", budget, 1<<20-6)
+	}
+
+	if _, _, err := storeTestData(files["2.in"], "p/2.in", &budget); !errors.Is(err, errDataTooLarge) {
+		t.Fatalf("bomb error = %v, want %v", err, errDataTooLarge)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"backend/common/database"
+)
+
+// cacheTimeout 单次缓存操作超时，Redis不可用时尽快回退到数据库
+const cacheTimeout = 200 * time.Millisecond
+
+// cacheGet 从Redis读取JSON缓存，未初始化Redis、未命中或出错时返回false
+func cacheGet(key string, dest interface{}) bool {
+	client := database.GetRedisClient()
+	if client == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	data, err := client.Get(ctx, key).Bytes()
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, dest) == nil
+}
+
+// cacheSet 将值以JSON形式写入Redis，失败只记录日志
+func cacheSet(key string, value interface{}, ttl time.Duration) {
+	client := database.GetRedisClient()
+	if client == nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error marshaling cache value %s: %v", key, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	if err := client.Set(ctx, key, data, ttl).Err(); err != nil {
+		log.Printf("Error writing cache %s: %v", key, err)
+	}
+}
+
+// cacheDelete 删除缓存
+func cacheDelete(keys ...string) {
+	client := database.GetRedisClient()
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	if err := client.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Error deleting cache %v: %v", keys, err)
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"backend/common/diagnostic"
+	"backend/common/language"
+	"backend/config"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 提交者可见的编译信息，对应 judge.compile_log_policy
+const (
+	compileLogFull        = "full"        // 编译输出与诊断信息
+	compileLogDiagnostics = "diagnostics" // 只有解析出的诊断信息
+	compileLogFirstError  = "first_error" // 只有第一条错误
+	compileLogNone        = "none"        // 不展示
+)
+
+// compileLogPolicy 当前用户可见的编译信息，管理员总是可见全部，未知的配置按 full 处理
+func compileLogPolicy(c *gin.Context) string {
+	if isAdmin(c) {
+		return compileLogFull
+	}
+	switch policy := config.GetConfig().Judge.CompileLogPolicy; policy {
+	case compileLogDiagnostics, compileLogFirstError, compileLogNone:
+		return policy
+	default:
+		return compileLogFull
+	}
+}
+
+// CompileLogView 提交的编译输出与诊断信息
+type CompileLogView struct {
+	Log         string                  `json:"compile_log"`
+	Truncated   bool                    `json:"truncated"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// RuntimeErrorView 第一个运行错误的用例的信号与诊断信息
+type RuntimeErrorView struct {
+	TestCaseID        uint                    `json:"test_case_id"`
+	Signal            string                  `json:"signal,omitempty"`
+	SignalDescription string                  `json:"signal_description,omitempty"`
+	Diagnostics       []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// diagnosticFormat 提交语言的编译器输出格式，语言已停用时按 gcc 解析
+func diagnosticFormat(submission *models.Submission) string {
+	if lang, ok := language.Get(submission.Language); ok {
+		return lang.Diagnostics
+	}
+	return ""
+}
+
+// compileOutput 提交的编译器输出，旧版判题机只在 error_message 中回传编译错误
+func compileOutput(submission *models.Submission) string {
+	if submission.CompileLog == "" && submission.Status == "compilation_error" {
+		return submission.ErrorMessage
+	}
+	return submission.CompileLog
+}
+
+// viewCompileLog 按可见性策略返回编译输出与诊断信息
+func viewCompileLog(submission *models.Submission, policy string) CompileLogView {
+	view := CompileLogView{Diagnostics: []diagnostic.Diagnostic{}}
+	if policy == compileLogNone {
+		return view
+	}
+
+	output := compileOutput(submission)
+	diagnostics := diagnostic.Parse(diagnosticFormat(submission), output)
+	if policy == compileLogFirstError {
+		if d := diagnostic.FirstError(diagnostics); d != nil {
+			view.Diagnostics = append(view.Diagnostics, *d)
+		}
+		return view
+	}
+	if diagnostics != nil {
+		view.Diagnostics = diagnostics
+	}
+	if policy == compileLogFull {
+		view.Log, view.Truncated = truncateLog(output, config.GetConfig().Judge.CompileLogMaxBytes)
+	}
+	return view
+}
+
+// truncateLog 按字节数截断，不截断 UTF-8 字符，maxBytes 为 0 时不截断
+func truncateLog(s string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s, false
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut], true
+}
+
+// restrictErrorMessage 按可见性策略替换编译错误提交的 error_message
+func restrictErrorMessage(c *gin.Context, submission *models.Submission) {
+	if submission.Status != "compilation_error" {
+		return
+	}
+	policy := compileLogPolicy(c)
+	view := viewCompileLog(submission, policy)
+	switch policy {
+	case compileLogFull:
+		submission.ErrorMessage, _ = truncateLog(submission.ErrorMessage, config.GetConfig().Judge.CompileLogMaxBytes)
+	default:
+		lines := make([]string, len(view.Diagnostics))
+		for i, d := range view.Diagnostics {
+			lines[i] = d.String()
+		}
+		submission.ErrorMessage = strings.Join(lines, "\n")
+	}
+}
+
+// GetCompileLog 获取提交的编译输出、解析出的诊断信息与运行错误的信号，仅提交者与管理员可见
+func GetCompileLog(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var submission models.Submission
+	if err := db.First(&submission, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Submission not found",
+		})
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	if submission.UserID != userID && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "You can only view the compile log of your own submissions",
+		})
+		return
+	}
+
+	// 封榜后的提交不展示编译信息与运行错误
+	masked, err := maskFrozenSubmissions(c, db, &submission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return
+	}
+	policy := compileLogPolicy(c)
+	if masked[submission.ID] {
+		policy = compileLogNone
+	}
+	response := gin.H{
+		"submission_id": submission.ID,
+		"status":        submission.Status,
+		"language":      submission.Language,
+		"policy":        policy,
+		"compile":       viewCompileLog(&submission, policy),
+		"runtime_error": nil,
+	}
+
+	if submission.Status == "runtime_error" {
+		var result models.TestCaseResult
+		err := db.Where("submission_id = ? AND status = ?", submission.ID, "runtime_error").
+			Order("id").First(&result).Error
+		if err == nil {
+			view := RuntimeErrorView{
+				TestCaseID:        result.TestCaseID,
+				Signal:            result.Signal,
+				SignalDescription: diagnostic.SignalDescription(result.Signal),
+				Diagnostics:       []diagnostic.Diagnostic{},
+			}
+			if policy != compileLogNone {
+				if diagnostics := diagnostic.Parse(diagnosticFormat(&submission), result.ErrorMessage); diagnostics != nil {
+					view.Diagnostics = diagnostics
+				}
+			}
+			response["runtime_error"] = view
+		} else if err != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch test case results",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// unfinishedContests 查询比赛中尚未结束的比赛
+func unfinishedContests(db *gorm.DB, contestIDs []uint, now time.Time) (map[uint]bool, error) {
+	unfinished := make(map[uint]bool)
+	if len(contestIDs) == 0 {
+		return unfinished, nil
+	}
+
+	var ids []uint
+	if err := db.Model(&models.Contest{}).Where("id IN ? AND end_time > ?", contestIDs, now).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		unfinished[id] = true
+	}
+	return unfinished, nil
+}
+
+// hideContestCode 比赛结束前普通用户看不到其他选手在比赛中提交的代码，管理员与提交者不受限制
+func hideContestCode(c *gin.Context, db *gorm.DB, submissions ...*models.Submission) error {
+	if isAdmin(c) {
+		return nil
+	}
+	userID, _ := getCurrentUserID(c)
+
+	var contestIDs []uint
+	for _, s := range submissions {
+		if s.ContestID != 0 && s.UserID != userID {
+			contestIDs = append(contestIDs, s.ContestID)
+		}
+	}
+	unfinished, err := unfinishedContests(db, contestIDs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, s := range submissions {
+		if s.UserID != userID && unfinished[s.ContestID] {
+			s.Code = ""
+		}
+	}
+	return nil
+}
+
+// canViewSubmissionDetail 比赛结束前比赛中提交的评测详情与状态推送仅提交者与管理员可见
+func canViewSubmissionDetail(c *gin.Context, db *gorm.DB, submission *models.Submission) (bool, error) {
+	if submission.ContestID == 0 || isAdmin(c) {
+		return true, nil
+	}
+	if userID, err := getCurrentUserID(c); err == nil && submission.UserID == userID {
+		return true, nil
+	}
+	unfinished, err := unfinishedContests(db, []uint{submission.ContestID}, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return !unfinished[submission.ContestID], nil
+}
+
+// unreleasedProblemIDs 属于尚未开始的比赛的题目ID子查询，这些题目在比赛开始前只对管理员可见
+func unreleasedProblemIDs(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.ContestProblem{}).Select("contest_problems.problem_id").
+		Joins("JOIN contests ON contests.id = contest_problems.contest_id AND contests.deleted_at IS NULL").
+		Where("contests.start_time > ?", now)
+}
+
+// problemReleased 题目是否不属于任何尚未开始的比赛
+func problemReleased(db *gorm.DB, problemID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.ContestProblem{}).
+		Joins("JOIN contests ON contests.id = contest_problems.contest_id AND contests.deleted_at IS NULL").
+		Where("contest_problems.problem_id = ? AND contests.start_time > ?", problemID, time.Now()).
+		Count(&count).Error
+	return count == 0, err
+}
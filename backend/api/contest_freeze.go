@@ -0,0 +1,110 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// frozenContests 查询比赛中当前处于封榜状态的比赛，返回比赛ID到封榜时间的映射
+func frozenContests(db *gorm.DB, contestIDs []uint, now time.Time) (map[uint]time.Time, error) {
+	frozen := make(map[uint]time.Time)
+	if len(contestIDs) == 0 {
+		return frozen, nil
+	}
+
+	var contests []models.Contest
+	if err := db.Where("id IN ? AND freeze_minutes > 0 AND unfrozen_at IS NULL", contestIDs).Find(&contests).Error; err != nil {
+		return nil, err
+	}
+	for i := range contests {
+		if contests[i].IsFrozen(now) {
+			freezeTime, _ := contests[i].FreezeTime()
+			frozen[contests[i].ID] = freezeTime
+		}
+	}
+	return frozen, nil
+}
+
+// notFrozenSubmission 排除封榜比赛中封榜后提交的查询条件，与 frozenContests、frozenAfter 的判断一致，用于按提交结果统计
+const notFrozenSubmission = "NOT EXISTS (SELECT 1 FROM contests WHERE contests.id = submissions.contest_id " +
+	"AND contests.deleted_at IS NULL AND contests.freeze_minutes > 0 AND contests.unfrozen_at IS NULL " +
+	"AND submissions.submitted_at >= contests.end_time - contests.freeze_minutes * INTERVAL '1 minute')"
+
+// invalidateContestStats 使比赛所有提交者的个人统计缓存失效，解除封榜后立即显示封榜期间的结果
+func invalidateContestStats(db *gorm.DB, contestID uint) {
+	var userIDs []uint
+	if err := db.Model(&models.Submission{}).Where("contest_id = ?", contestID).Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		log.Printf("Error loading participants of contest %d: %v", contestID, err)
+		return
+	}
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = dashboardStatsKey(id)
+	}
+	if len(keys) > 0 {
+		cacheDelete(keys...)
+	}
+}
+
+// frozenAfter 提交是否是封榜比赛中封榜后的提交
+func frozenAfter(frozen map[uint]time.Time, contestID uint, submittedAt time.Time) bool {
+	freezeTime, ok := frozen[contestID]
+	return ok && !submittedAt.Before(freezeTime)
+}
+
+// maskFrozenSubmissions 封榜期间对普通用户隐藏封榜后提交的评测结果，与榜单一致显示为等待中，
+// 返回被隐藏的提交ID，调用方据此隐藏测试用例结果与状态事件。管理员始终看到真实结果
+func maskFrozenSubmissions(c *gin.Context, db *gorm.DB, submissions ...*models.Submission) (map[uint]bool, error) {
+	masked := make(map[uint]bool)
+	if isAdmin(c) {
+		return masked, nil
+	}
+
+	var contestIDs []uint
+	for _, s := range submissions {
+		if s.ContestID != 0 {
+			contestIDs = append(contestIDs, s.ContestID)
+		}
+	}
+	frozen, err := frozenContests(db, contestIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range submissions {
+		if !frozenAfter(frozen, s.ContestID, s.SubmittedAt) {
+			continue
+		}
+		masked[s.ID] = true
+		s.Status = "pending"
+		s.Score = 0
+		s.RunTime = 0
+		s.Memory = 0
+		s.ErrorMessage = ""
+		s.CompileLog = ""
+		s.JudgedAt = time.Time{}
+		s.JudgeRunID = ""
+		s.TestCaseResults = nil
+	}
+	return masked, nil
+}
+
+// submissionFrozen 提交的评测结果是否因封榜对当前用户隐藏，不修改 submission
+func submissionFrozen(c *gin.Context, db *gorm.DB, submission *models.Submission) (bool, error) {
+	s := *submission
+	masked, err := maskFrozenSubmissions(c, db, &s)
+	return masked[submission.ID], err
+}
+
+// maskSubmissionEvent 隐藏状态事件中的评测进度与结果，只保留评测是否结束
+func maskSubmissionEvent(event SubmissionEvent) SubmissionEvent {
+	return SubmissionEvent{
+		SubmissionID: event.SubmissionID,
+		Status:       "pending",
+		Final:        event.Final,
+	}
+}
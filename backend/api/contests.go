@@ -0,0 +1,442 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// contestRequest 创建或更新比赛的请求
+type contestRequest struct {
+	Title         string    `json:"title" binding:"required"`
+	Description   string    `json:"description"`
+	StartTime     time.Time `json:"start_time" binding:"required"`
+	EndTime       time.Time `json:"end_time" binding:"required"`
+	RuleType      string    `json:"rule_type"`
+	FreezeMinutes int       `json:"freeze_minutes"` // 比赛结束前封榜的分钟数
+	Problems      []struct {
+		ProblemID uint   `json:"problem_id"`
+		Label     string `json:"label"`
+	} `json:"problems"`
+}
+
+// validate 校验比赛参数与题目列表
+func (r *contestRequest) validate(db *gorm.DB) error {
+	if !r.EndTime.After(r.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	switch r.RuleType {
+	case "":
+		r.RuleType = "icpc"
+	case "icpc", "oi":
+	default:
+		return errors.New("rule_type must be icpc or oi")
+	}
+	if r.FreezeMinutes < 0 || time.Duration(r.FreezeMinutes)*time.Minute > r.EndTime.Sub(r.StartTime) {
+		return errors.New("freeze_minutes must be between 0 and the contest duration")
+	}
+
+	labels := make(map[string]bool, len(r.Problems))
+	ids := make([]uint, 0, len(r.Problems))
+	for _, p := range r.Problems {
+		if p.Label == "" || labels[p.Label] {
+			return fmt.Errorf("problem labels must be unique and non-empty: %q", p.Label)
+		}
+		labels[p.Label] = true
+		ids = append(ids, p.ProblemID)
+	}
+
+	if len(ids) > 0 {
+		var count int64
+		if err := db.Model(&models.Problem{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return errors.New("problems must exist and not repeat")
+		}
+	}
+	return nil
+}
+
+// contestProblems 将请求中的题目列表转换为模型
+func (r *contestRequest) contestProblems(contestID uint) []models.ContestProblem {
+	problems := make([]models.ContestProblem, 0, len(r.Problems))
+	for _, p := range r.Problems {
+		problems = append(problems, models.ContestProblem{ContestID: contestID, ProblemID: p.ProblemID, Label: p.Label})
+	}
+	return problems
+}
+
+// findContest 查找路由参数指定的比赛，不存在时写入404响应
+func findContest(c *gin.Context, db *gorm.DB) (*models.Contest, bool) {
+	var contest models.Contest
+	if err := db.First(&contest, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Contest not found",
+		})
+		return nil, false
+	}
+	return &contest, true
+}
+
+// isRegistered 用户是否已报名比赛
+func isRegistered(db *gorm.DB, contestID, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&models.ContestRegistration{}).Where("contest_id = ? AND user_id = ?", contestID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetContests 获取比赛列表
+func GetContests(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var contests []models.Contest
+	if err := db.Order("start_time DESC").Find(&contests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch contests",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"contests": contests,
+	})
+}
+
+// GetContest 获取比赛详情，比赛开始前题目只对管理员可见
+func GetContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	registered, err := isRegistered(db, contest.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch registration",
+		})
+		return
+	}
+
+	if isAdmin(c) || !time.Now().Before(contest.StartTime) {
+		if err := db.Preload("Problem", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, title, difficulty, time_limit, memory_limit")
+		}).Where("contest_id = ?", contest.ID).Order("label").Find(&contest.Problems).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch contest problems",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contest":    contest,
+		"registered": registered,
+	})
+}
+
+// CreateContest 创建比赛（管理员）
+func CreateContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var req contestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	if err := req.validate(db); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	contest := models.Contest{
+		Title:         req.Title,
+		Description:   req.Description,
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		RuleType:      req.RuleType,
+		FreezeMinutes: req.FreezeMinutes,
+		CreatedBy:     userID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&contest).Error; err != nil {
+			return err
+		}
+		contest.Problems = req.contestProblems(contest.ID)
+		if len(contest.Problems) == 0 {
+			return nil
+		}
+		return tx.Create(&contest.Problems).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create contest: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contest created successfully",
+		"contest": contest,
+		"status":  "success",
+	})
+}
+
+// UpdateContest 更新比赛，并以请求中的题目列表替换原有题目（管理员）
+func UpdateContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	var req contestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	if err := req.validate(db); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	contest.Title = req.Title
+	contest.Description = req.Description
+	contest.StartTime = req.StartTime
+	contest.EndTime = req.EndTime
+	contest.RuleType = req.RuleType
+	contest.FreezeMinutes = req.FreezeMinutes
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(contest).Error; err != nil {
+			return err
+		}
+		// 题号有唯一索引，旧记录需要物理删除
+		if err := tx.Unscoped().Where("contest_id = ?", contest.ID).Delete(&models.ContestProblem{}).Error; err != nil {
+			return err
+		}
+		contest.Problems = req.contestProblems(contest.ID)
+		if len(contest.Problems) == 0 {
+			return nil
+		}
+		return tx.Create(&contest.Problems).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update contest: " + err.Error(),
+		})
+		return
+	}
+	invalidateScoreboard(contest.ID)
+	invalidateContestStats(db, contest.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contest updated successfully",
+		"contest": contest,
+		"status":  "success",
+	})
+}
+
+// DeleteContest 删除比赛（管理员）
+func DeleteContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	if err := db.Delete(contest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete contest: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contest deleted successfully",
+		"id":      contest.ID,
+		"status":  "success",
+	})
+}
+
+// UnfreezeContest 赛后解除封榜，选手随即看到最终榜单（管理员）
+func UnfreezeContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	if time.Now().Before(contest.EndTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Contest has not ended yet",
+		})
+		return
+	}
+
+	if err := db.Model(contest).Update("unfrozen_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to unfreeze contest: " + err.Error(),
+		})
+		return
+	}
+	invalidateScoreboard(contest.ID)
+	invalidateContestStats(db, contest.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contest unfrozen successfully",
+		"contest": contest,
+		"status":  "success",
+	})
+}
+
+// RegisterContest 报名比赛，比赛结束前均可报名
+func RegisterContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	if !time.Now().Before(contest.EndTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Contest has ended",
+		})
+		return
+	}
+
+	registered, err := isRegistered(db, contest.ID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch registration",
+		})
+		return
+	}
+	if registered {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Already registered",
+		})
+		return
+	}
+
+	if err := db.Create(&models.ContestRegistration{ContestID: contest.ID, UserID: userID}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to register: " + err.Error(),
+		})
+		return
+	}
+	invalidateScoreboard(contest.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Registered successfully",
+		"status":  "success",
+	})
+}
+
+// UnregisterContest 取消报名，仅限比赛开始前
+func UnregisterContest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	if !time.Now().Before(contest.StartTime) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Contest has already started",
+		})
+		return
+	}
+
+	if err := db.Unscoped().Where("contest_id = ? AND user_id = ?", contest.ID, userID).
+		Delete(&models.ContestRegistration{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to unregister: " + err.Error(),
+		})
+		return
+	}
+	invalidateScoreboard(contest.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Unregistered successfully",
+		"status":  "success",
+	})
+}
+
+// checkContestSubmission 校验比赛内提交：比赛进行中、已报名且题目属于比赛，管理员不受限制
+func checkContestSubmission(c *gin.Context, db *gorm.DB, submission *models.Submission) (int, error) {
+	var contest models.Contest
+	if err := db.First(&contest, submission.ContestID).Error; err != nil {
+		return http.StatusNotFound, errors.New("contest not found")
+	}
+
+	var count int64
+	if err := db.Model(&models.ContestProblem{}).
+		Where("contest_id = ? AND problem_id = ?", contest.ID, submission.ProblemID).
+		Count(&count).Error; err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if count == 0 {
+		return http.StatusBadRequest, errors.New("problem is not part of the contest")
+	}
+
+	if isAdmin(c) {
+		return http.StatusOK, nil
+	}
+
+	now := time.Now()
+	if now.Before(contest.StartTime) || !now.Before(contest.EndTime) {
+		return http.StatusForbidden, errors.New("contest is not running")
+	}
+	registered, err := isRegistered(db, contest.ID, submission.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !registered {
+		return http.StatusForbidden, errors.New("not registered for the contest")
+	}
+	return http.StatusOK, nil
+}
@@ -41,3 +41,9 @@ func getCurrentUserID(c *gin.Context) (uint, error) {
 		return 0, fmt.Errorf("unsupported user id type %T", v)
 	}
 }
+
+// isAdmin 当前用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	role, exists := c.Get("role")
+	return exists && role == "admin"
+}
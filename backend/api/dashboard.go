@@ -0,0 +1,216 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// dashboardStatsTTL 个人统计缓存时间，判题结果写回时主动失效
+	dashboardStatsTTL = 5 * time.Minute
+	// dashboardActivitiesTTL 全站动态缓存时间
+	dashboardActivitiesTTL = 30 * time.Second
+	// dashboardActivitiesKey 全站动态缓存键
+	dashboardActivitiesKey = "dashboard:activities"
+	// maxActivities 动态条数
+	maxActivities = 20
+	// heatmapDays 提交热力图覆盖的天数
+	heatmapDays = 365
+)
+
+// DashboardStats 个人统计数据
+type DashboardStats struct {
+	TotalProblems       int64            `json:"total_problems"`
+	SolvedProblems      int64            `json:"solved_problems"`
+	TotalSubmissions    int64            `json:"total_submissions"`
+	AcceptedSubmissions int64            `json:"accepted_submissions"`
+	AcceptanceRate      float64          `json:"acceptance_rate"`      // 百分比，保留两位小数
+	SolvedByDifficulty  map[string]int64 `json:"solved_by_difficulty"` // 难度 -> 通过题数
+	Heatmap             []HeatmapDay     `json:"heatmap"`              // 只包含有提交的日期
+}
+
+// HeatmapDay 单日提交数
+type HeatmapDay struct {
+	Date  string `json:"date"` // 2006-01-02
+	Count int64  `json:"count"`
+}
+
+// Activity 全站动态
+type Activity struct {
+	Type         string    `json:"type"` // problem: 新题目, submission: 通过的提交
+	ProblemID    uint      `json:"problem_id"`
+	ProblemTitle string    `json:"problem_title"`
+	SubmissionID uint      `json:"submission_id,omitempty"`
+	UserID       uint      `json:"user_id,omitempty"`
+	Username     string    `json:"username,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// dashboardStatsKey 个人统计缓存键
+func dashboardStatsKey(userID uint) string {
+	return fmt.Sprintf("dashboard:stats:%d", userID)
+}
+
+// GetDashboardStats 获取当前用户的统计数据
+func GetDashboardStats(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var stats DashboardStats
+	key := dashboardStatsKey(userID)
+	if !cacheGet(key, &stats) {
+		db := c.MustGet("db").(*gorm.DB)
+		if err := loadDashboardStats(db, userID, &stats); err != nil {
+			log.Printf("Error loading dashboard stats: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取统计数据失败"})
+			return
+		}
+		cacheSet(key, &stats, dashboardStatsTTL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": stats,
+	})
+}
+
+// loadDashboardStats 从数据库统计用户数据
+func loadDashboardStats(db *gorm.DB, userID uint, stats *DashboardStats) error {
+	if err := db.Model(&models.Problem{}).Count(&stats.TotalProblems).Error; err != nil {
+		return err
+	}
+
+	submissions := db.Model(&models.Submission{}).Where("user_id = ?", userID).Session(&gorm.Session{})
+	if err := submissions.Count(&stats.TotalSubmissions).Error; err != nil {
+		return err
+	}
+	// 封榜比赛中封榜后的通过在解除封榜前不计入，与提交列表中显示为等待中一致
+	if err := submissions.Where("status = ?", "accepted").Where(notFrozenSubmission).Count(&stats.AcceptedSubmissions).Error; err != nil {
+		return err
+	}
+	if stats.TotalSubmissions > 0 {
+		rate := float64(stats.AcceptedSubmissions) * 100 / float64(stats.TotalSubmissions)
+		stats.AcceptanceRate = float64(int(rate*100+0.5)) / 100
+	}
+
+	// 按难度统计通过的题目数
+	var solved []struct {
+		Difficulty string
+		Count      int64
+	}
+	if err := db.Table("submissions").
+		Select("problems.difficulty AS difficulty, COUNT(DISTINCT submissions.problem_id) AS count").
+		Joins("JOIN problems ON problems.id = submissions.problem_id AND problems.deleted_at IS NULL").
+		Where("submissions.user_id = ? AND submissions.status = ? AND submissions.deleted_at IS NULL", userID, "accepted").
+		Where(notFrozenSubmission).
+		Group("problems.difficulty").
+		Scan(&solved).Error; err != nil {
+		return err
+	}
+	stats.SolvedByDifficulty = make(map[string]int64, len(solved))
+	for _, row := range solved {
+		stats.SolvedByDifficulty[row.Difficulty] = row.Count
+		stats.SolvedProblems += row.Count
+	}
+
+	// 最近一年每日提交数
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day()-heatmapDays+1, 0, 0, 0, 0, now.Location())
+	stats.Heatmap = []HeatmapDay{}
+	return db.Table("submissions").
+		Select("TO_CHAR(submitted_at, 'YYYY-MM-DD') AS date, COUNT(*) AS count").
+		Where("user_id = ? AND submitted_at >= ? AND deleted_at IS NULL", userID, since).
+		Group("date").
+		Order("date").
+		Scan(&stats.Heatmap).Error
+}
+
+// GetRecentActivities 获取全站最近动态：新发布的题目与通过的提交
+func GetRecentActivities(c *gin.Context) {
+	var activities []Activity
+	if !cacheGet(dashboardActivitiesKey, &activities) {
+		db := c.MustGet("db").(*gorm.DB)
+		var err error
+		if activities, err = loadRecentActivities(db); err != nil {
+			log.Printf("Error loading recent activities: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取最近动态失败"})
+			return
+		}
+		cacheSet(dashboardActivitiesKey, activities, dashboardActivitiesTTL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+	})
+}
+
+// loadRecentActivities 合并最近的题目与通过的提交，按时间倒序
+func loadRecentActivities(db *gorm.DB) ([]Activity, error) {
+	// 动态对所有用户相同，尚未开始的比赛中的题目不出现在动态中
+	var problems []models.Problem
+	if err := db.Select("id, title, created_at").Where("id NOT IN (?)", unreleasedProblemIDs(db, time.Now())).
+		Order("created_at DESC").Limit(maxActivities).Find(&problems).Error; err != nil {
+		return nil, err
+	}
+
+	activities := make([]Activity, 0, 2*maxActivities)
+	for _, problem := range problems {
+		activities = append(activities, Activity{
+			Type:         "problem",
+			ProblemID:    problem.ID,
+			ProblemTitle: problem.Title,
+			Time:         problem.CreatedAt,
+		})
+	}
+
+	var accepted []struct {
+		Activity
+		ContestID uint
+	}
+	if err := db.Table("submissions").
+		Select("'submission' AS type, submissions.id AS submission_id, submissions.problem_id, problems.title AS problem_title, "+
+			"submissions.user_id, users.username, submissions.status, submissions.submitted_at AS time, submissions.contest_id").
+		Joins("JOIN problems ON problems.id = submissions.problem_id AND problems.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = submissions.user_id").
+		Where("submissions.status = ? AND submissions.deleted_at IS NULL", "accepted").
+		Order("submissions.submitted_at DESC").
+		Limit(maxActivities).
+		Scan(&accepted).Error; err != nil {
+		return nil, err
+	}
+
+	// 动态对所有用户相同，封榜比赛中封榜后的通过不出现在动态中
+	var contestIDs []uint
+	for _, a := range accepted {
+		if a.ContestID != 0 {
+			contestIDs = append(contestIDs, a.ContestID)
+		}
+	}
+	frozen, err := frozenContests(db, contestIDs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accepted {
+		if !frozenAfter(frozen, a.ContestID, a.Time) {
+			activities = append(activities, a.Activity)
+		}
+	}
+
+	sort.SliceStable(activities, func(i, j int) bool {
+		return activities[i].Time.After(activities[j].Time)
+	})
+	if len(activities) > maxActivities {
+		activities = activities[:maxActivities]
+	}
+	return activities, nil
+}
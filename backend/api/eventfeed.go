@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/common/language"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// clicsTimeLayout CLICS 绝对时间格式
+const clicsTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// clicsJudgementTypes 提交状态到 CLICS 判题类型的映射，未列出的状态（等待中、系统错误）不输出判题结果
+var clicsJudgementTypes = map[string]string{
+	"accepted":              "AC",
+	"wrong_answer":          "WA",
+	"time_limit_exceeded":   "TLE",
+	"memory_limit_exceeded": "MLE",
+	"runtime_error":         "RTE",
+	"compilation_error":     "CE",
+}
+
+// clicsEvent CLICS 事件流中的一条事件
+type clicsEvent struct {
+	Type  string      `json:"type"`
+	ID    *string     `json:"id"`
+	Data  interface{} `json:"data"`
+	Token string      `json:"token"`
+}
+
+// clicsTime 格式化绝对时间，零值输出 null
+func clicsTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(clicsTimeLayout)
+}
+
+// clicsRelTime 格式化相对时间 h:mm:ss.uuu
+func clicsRelTime(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%s%d:%02d:%02d.%03d", sign, ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// GetContestEventFeed 以 CLICS 事件流（NDJSON）导出 ICPC 比赛的完整数据，包含封榜后的真实结果，
+// 可直接导入 ICPC Tools Resolver 进行滚榜（管理员）
+func GetContestEventFeed(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+	if contest.RuleType != "icpc" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Event feed is only available for ICPC contests",
+		})
+		return
+	}
+
+	events, err := buildEventFeed(db, contest, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to build event feed: " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for i := range events {
+		if err := encoder.Encode(&events[i]); err != nil {
+			return
+		}
+	}
+}
+
+// buildEventFeed 根据比赛、题目、选手与提交生成事件
+func buildEventFeed(db *gorm.DB, contest *models.Contest, now time.Time) ([]clicsEvent, error) {
+	var events []clicsEvent
+	add := func(eventType string, id string, data interface{}) {
+		event := clicsEvent{Type: eventType, Data: data, Token: strconv.Itoa(len(events) + 1)}
+		if id != "" {
+			event.ID = &id
+		}
+		events = append(events, event)
+	}
+	contestTime := func(t time.Time) string {
+		return clicsRelTime(t.Sub(contest.StartTime))
+	}
+
+	// 比赛信息
+	contestData := map[string]interface{}{
+		"id":              strconv.FormatUint(uint64(contest.ID), 10),
+		"name":            contest.Title,
+		"formal_name":     contest.Title,
+		"start_time":      clicsTime(contest.StartTime),
+		"duration":        clicsRelTime(contest.EndTime.Sub(contest.StartTime)),
+		"penalty_time":    icpcPenaltyMinutes,
+		"scoreboard_type": "pass-fail",
+	}
+	if contest.FreezeMinutes > 0 {
+		contestData["scoreboard_freeze_duration"] = clicsRelTime(time.Duration(contest.FreezeMinutes) * time.Minute)
+	}
+	add("contest", "", contestData)
+
+	// 判题类型
+	for _, jt := range []struct {
+		id, name        string
+		penalty, solved bool
+	}{
+		{"AC", "correct", false, true},
+		{"WA", "wrong answer", true, false},
+		{"TLE", "time limit exceeded", true, false},
+		{"MLE", "memory limit exceeded", true, false},
+		{"RTE", "run-time error", true, false},
+		{"CE", "compiler error", false, false},
+	} {
+		add("judgement-types", jt.id, map[string]interface{}{
+			"id": jt.id, "name": jt.name, "penalty": jt.penalty, "solved": jt.solved,
+		})
+	}
+
+	// 提交
+	var submissions []models.Submission
+	if err := db.Select("id, user_id, problem_id, language, status, submitted_at, judged_at").
+		Where("contest_id = ? AND submitted_at >= ? AND submitted_at < ?", contest.ID, contest.StartTime, contest.EndTime).
+		Order("submitted_at, id").
+		Find(&submissions).Error; err != nil {
+		return nil, err
+	}
+
+	// 语言
+	languages := map[string]bool{}
+	for _, s := range submissions {
+		if !languages[s.Language] {
+			languages[s.Language] = true
+			name := s.Language
+			if lang, ok := language.Get(s.Language); ok {
+				name = lang.Name
+			}
+			add("languages", s.Language, map[string]interface{}{"id": s.Language, "name": name})
+		}
+	}
+
+	// 题目
+	var problems []struct {
+		ProblemID     uint
+		Label         string
+		Title         string
+		TestDataCount int
+	}
+	if err := db.Table("contest_problems").
+		Select("contest_problems.problem_id, contest_problems.label, problems.title, "+
+			"(SELECT COUNT(*) FROM test_cases WHERE test_cases.problem_id = problems.id AND test_cases.deleted_at IS NULL) AS test_data_count").
+		Joins("JOIN problems ON problems.id = contest_problems.problem_id").
+		Where("contest_problems.contest_id = ? AND contest_problems.deleted_at IS NULL", contest.ID).
+		Order("contest_problems.label").
+		Scan(&problems).Error; err != nil {
+		return nil, err
+	}
+	for i, p := range problems {
+		id := strconv.FormatUint(uint64(p.ProblemID), 10)
+		add("problems", id, map[string]interface{}{
+			"id": id, "label": p.Label, "name": p.Title, "ordinal": i, "test_data_count": p.TestDataCount,
+		})
+	}
+
+	// 队伍，即已报名或有提交的用户
+	var teams []struct {
+		ID       uint
+		Username string
+	}
+	if err := db.Table("users").Select("users.id, users.username").
+		Where("users.id IN (?) OR users.id IN (?)",
+			db.Model(&models.ContestRegistration{}).Select("user_id").Where("contest_id = ?", contest.ID),
+			db.Model(&models.Submission{}).Select("user_id").Where("contest_id = ?", contest.ID)).
+		Order("users.id").
+		Scan(&teams).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		id := strconv.FormatUint(uint64(t.ID), 10)
+		add("teams", id, map[string]interface{}{"id": id, "name": t.Username, "group_ids": []string{}})
+	}
+
+	// 提交与判题结果
+	pending := false
+	for _, s := range submissions {
+		id := strconv.FormatUint(uint64(s.ID), 10)
+		add("submissions", id, map[string]interface{}{
+			"id":           id,
+			"language_id":  s.Language,
+			"problem_id":   strconv.FormatUint(uint64(s.ProblemID), 10),
+			"team_id":      strconv.FormatUint(uint64(s.UserID), 10),
+			"time":         clicsTime(s.SubmittedAt),
+			"contest_time": contestTime(s.SubmittedAt),
+			"files":        []interface{}{},
+		})
+
+		judgementType, judged := clicsJudgementTypes[s.Status]
+		if !judged {
+			pending = pending || isPendingStatus(s.Status)
+			continue
+		}
+		end := s.JudgedAt
+		if end.Before(s.SubmittedAt) {
+			end = s.SubmittedAt
+		}
+		add("judgements", id, map[string]interface{}{
+			"id":                 id,
+			"submission_id":      id,
+			"judgement_type_id":  judgementType,
+			"start_time":         clicsTime(s.SubmittedAt),
+			"start_contest_time": contestTime(s.SubmittedAt),
+			"end_time":           clicsTime(end),
+			"end_contest_time":   contestTime(end),
+		})
+	}
+
+	// 比赛状态，比赛结束且所有提交评测完成后视为结果已确定
+	state := map[string]interface{}{
+		"started":        nil,
+		"frozen":         nil,
+		"ended":          nil,
+		"thawed":         nil,
+		"finalized":      nil,
+		"end_of_updates": nil,
+	}
+	if !now.Before(contest.StartTime) {
+		state["started"] = clicsTime(contest.StartTime)
+	}
+	if freezeTime, ok := contest.FreezeTime(); ok && !now.Before(freezeTime) {
+		state["frozen"] = clicsTime(freezeTime)
+	}
+	if !now.Before(contest.EndTime) {
+		state["ended"] = clicsTime(contest.EndTime)
+		if contest.UnfrozenAt != nil {
+			state["thawed"] = clicsTime(*contest.UnfrozenAt)
+		}
+		if !pending {
+			state["finalized"] = clicsTime(now)
+			state["end_of_updates"] = clicsTime(now)
+		}
+	}
+	add("state", "", state)
+
+	return events, nil
+}
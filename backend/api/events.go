@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"backend/common/database"
+)
+
+// SubmissionEvent 提交状态变化事件，推送给订阅该提交的客户端
+type SubmissionEvent struct {
+	SubmissionID uint    `json:"submission_id"`
+	Status       string  `json:"status"`                // pending, 判题进度阶段或最终状态
+	Case         int     `json:"case,omitempty"`        // 正在评测的用例序号，从 1 开始
+	Total        int     `json:"total,omitempty"`       // 用例总数
+	CaseStatus   string  `json:"case_status,omitempty"` // case_result 阶段的用例结果
+	Score        float64 `json:"score"`
+	RunTime      int     `json:"run_time"` // 毫秒
+	Memory       int     `json:"memory"`   // KB
+	Final        bool    `json:"final"`    // 为真时评测已结束，不会再有后续事件
+}
+
+// submissionChannel 提交事件的 Redis 频道
+func submissionChannel(submissionID uint) string {
+	return fmt.Sprintf("submission:events:%d", submissionID)
+}
+
+// localSubscribers 未启用 Redis 时的进程内订阅者，仅适用于单实例部署
+var localSubscribers = struct {
+	sync.Mutex
+	m map[uint]map[chan SubmissionEvent]struct{}
+}{m: make(map[uint]map[chan SubmissionEvent]struct{})}
+
+// publishSubmissionEvent 发布提交事件。启用 Redis 时经 pub/sub 分发到所有 API 实例，否则只分发给本进程的订阅者
+func publishSubmissionEvent(event SubmissionEvent) {
+	client := database.GetRedisClient()
+	if client == nil {
+		publishLocal(event)
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling submission event: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+	if err := client.Publish(ctx, submissionChannel(event.SubmissionID), data).Err(); err != nil {
+		log.Printf("Error publishing submission event: %v", err)
+	}
+}
+
+// publishLocal 分发给本进程的订阅者，订阅者处理不及时则丢弃事件
+func publishLocal(event SubmissionEvent) {
+	localSubscribers.Lock()
+	defer localSubscribers.Unlock()
+	for ch := range localSubscribers.m[event.SubmissionID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// subscribeSubmission 订阅提交事件，ctx 取消或调用返回的函数后停止订阅
+func subscribeSubmission(ctx context.Context, submissionID uint) (<-chan SubmissionEvent, func()) {
+	events := make(chan SubmissionEvent, 16)
+
+	client := database.GetRedisClient()
+	if client == nil {
+		localSubscribers.Lock()
+		subs := localSubscribers.m[submissionID]
+		if subs == nil {
+			subs = make(map[chan SubmissionEvent]struct{})
+			localSubscribers.m[submissionID] = subs
+		}
+		subs[events] = struct{}{}
+		localSubscribers.Unlock()
+
+		return events, func() {
+			localSubscribers.Lock()
+			defer localSubscribers.Unlock()
+			delete(subs, events)
+			if len(subs) == 0 {
+				delete(localSubscribers.m, submissionID)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pubsub := client.Subscribe(ctx, submissionChannel(submissionID))
+	// 等待订阅确认，避免在订阅生效前读取快照而错过事件
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Printf("Error subscribing to submission events: %v", err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event SubmissionEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					log.Printf("Error unmarshaling submission event: %v", err)
+					continue
+				}
+				select {
+				case events <- event:
+				default:
+				}
+			}
+		}
+	}()
+
+	return events, cancel
+}
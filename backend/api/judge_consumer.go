@@ -3,23 +3,40 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"backend/common/scoring"
 	"backend/config"
 	"backend/models"
-	"github.com/IBM/sarama"
+	"backend/queue"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 结果主题中的消息类型
+const (
+	// MessageTypeResult 最终判题结果
+	MessageTypeResult = "result"
+	// MessageTypeProgress 判题进度
+	MessageTypeProgress = "progress"
+	// MessageTypeRunResult 自定义输入运行结果
+	MessageTypeRunResult = "run_result"
 )
 
 // JudgeResult 判题结果结构
 type JudgeResult struct {
+	Type         string           `json:"type"`   // result，旧版判题机不带该字段
+	RunID        string           `json:"run_id"` // 判题任务中的评测ID，旧版API服务的任务不带评测ID时由判题机生成
 	SubmissionID uint             `json:"submission_id"`
 	ProblemID    uint             `json:"problem_id"`
 	Status       string           `json:"status"`   // accepted, wrong_answer, time_limit_exceeded, memory_limit_exceeded, runtime_error, compilation_error
 	RunTime      int              `json:"run_time"` // 毫秒
 	Memory       int              `json:"memory"`   // KB
 	ErrorMessage string           `json:"error_message"`
+	CompileLog   string           `json:"compile_log,omitempty"` // 编译器输出，编译成功时可能包含警告
 	TestCases    []TestCaseResult `json:"test_cases"`
 }
 
@@ -29,39 +46,48 @@ type TestCaseResult struct {
 	Input          string `json:"input"`
 	ExpectedOutput string `json:"expected_output"`
 	UserOutput     string `json:"user_output"`
-	Status         string `json:"status"`   // passed, failed, time_limit_exceeded, memory_limit_exceeded, runtime_error
+	Status         string `json:"status"`   // passed, failed, time_limit_exceeded, memory_limit_exceeded, runtime_error, skipped
 	RunTime        int    `json:"run_time"` // 毫秒
 	Memory         int    `json:"memory"`   // KB
 	ErrorMessage   string `json:"error_message"`
+	Signal         string `json:"signal,omitempty"` // 导致程序退出的信号，例如 SIGSEGV
 }
 
-// InitJudgeResultConsumer 初始化判题结果消费者
-func InitJudgeResultConsumer(db *gorm.DB) error {
-	cfg := config.GetConfig()
+// 判题进度阶段
+const (
+	// StageCompiling 开始编译
+	StageCompiling = "compiling"
+	// StageCompiled 编译完成
+	StageCompiled = "compiled"
+	// StageRunning 开始评测一个用例
+	StageRunning = "running"
+	// StageCaseResult 一个用例评测完成，携带用例结果
+	StageCaseResult = "case_result"
+)
 
-	// 创建消费者配置
-	kafkaConfig := sarama.NewConfig()
-	kafkaConfig.Consumer.Return.Errors = true
+// JudgeProgress 判题进度，与判题结果发送到同一主题并使用相同的键，保证同一提交的消息有序
+type JudgeProgress struct {
+	Type         string          `json:"type"` // progress
+	RunID        string          `json:"run_id"`
+	SubmissionID uint            `json:"submission_id"`
+	Stage        string          `json:"stage"`               // compiling, compiled, running, case_result
+	Case         int             `json:"case"`                // 用例序号，从 1 开始
+	Total        int             `json:"total"`               // 用例总数
+	TestCase     *TestCaseResult `json:"test_case,omitempty"` // case_result 阶段的用例结果
+}
 
-	// 创建消费者组
-	group, err := sarama.NewConsumerGroup(cfg.Kafka.Brokers, "judge-result-group", kafkaConfig)
-	if err != nil {
-		return err
+// InitJudgeResultConsumer 初始化判题结果消费者
+func InitJudgeResultConsumer(db *gorm.DB) error {
+	if JudgeQueue == nil {
+		return errors.New("judge queue is not initialized")
 	}
+	cfg := config.GetConfig()
+	consumer := &JudgeResultConsumer{db: db}
 
 	// 启动消费者协程
 	go func() {
-		defer func() {
-			if err := group.Close(); err != nil {
-				log.Printf("Error closing consumer group: %v", err)
-			}
-		}()
-
-		ctx := context.Background()
-		for {
-			if err := group.Consume(ctx, []string{cfg.Kafka.ResultTopic}, &JudgeResultConsumer{db: db}); err != nil {
-				log.Printf("Error consuming judge results: %v", err)
-			}
+		if err := JudgeQueue.Consume(context.Background(), "judge-result-group", []string{cfg.Kafka.ResultTopic}, consumer.Handle); err != nil {
+			log.Printf("Error consuming judge results: %v", err)
 		}
 	}()
 
@@ -73,45 +99,139 @@ type JudgeResultConsumer struct {
 	db *gorm.DB
 }
 
-// Setup ConsumerGroupHandler接口实现
-func (c *JudgeResultConsumer) Setup(sarama.ConsumerGroupSession) error {
+// Handle 处理结果主题中的一条消息。
+// 消息处理成功或已送入死信主题后才确认，处理期间消费结束时消息会被重新投递
+func (c *JudgeResultConsumer) Handle(ctx context.Context, message *queue.Message) error {
+	attempts, err := c.handleWithRetry(ctx, message)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		log.Printf("Error processing judge result %s after %d attempts: %v", message.ID, attempts, err)
+		if err := c.sendToDeadLetter(ctx, message, err, attempts); err != nil {
+			log.Printf("Error sending judge result to dead letter topic: %v", err)
+			return err
+		}
+	}
 	return nil
 }
 
-// Cleanup ConsumerGroupHandler接口实现
-func (c *JudgeResultConsumer) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
+// permanentError 重试也无法成功的错误，直接送入死信主题
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// retryable 判断处理失败的消息是否值得重试
+func retryable(err error) bool {
+	var permanent *permanentError
+	return !errors.As(err, &permanent) && !errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// maxResultRetryDelay 重试间隔上限
+const maxResultRetryDelay = 30 * time.Second
+
+// handleWithRetry 处理消息，失败时按指数退避重试，返回尝试次数与最后一次的错误
+func (c *JudgeResultConsumer) handleWithRetry(ctx context.Context, message *queue.Message) (int, error) {
+	cfg := config.GetConfig()
+	delay := time.Duration(cfg.Kafka.ResultRetryDelay) * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		err := c.handleMessage(message)
+		if err == nil || !retryable(err) || attempt > cfg.Kafka.ResultMaxRetries {
+			return attempt, err
+		}
+
+		log.Printf("Error processing judge result %s (attempt %d), retrying in %v: %v", message.ID, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxResultRetryDelay {
+			delay = maxResultRetryDelay
+		}
+	}
 }
 
-// ConsumeClaim ConsumerGroupHandler接口实现
-func (c *JudgeResultConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for message := range claim.Messages() {
-		log.Printf("Received judge result: %s", string(message.Value))
+// handleMessage 按类型处理结果主题中的一条消息
+func (c *JudgeResultConsumer) handleMessage(message *queue.Message) error {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(message.Value, &envelope); err != nil {
+		return &permanentError{fmt.Errorf("unmarshal message: %w", err)}
+	}
 
-		// 解析判题结果
-		var result JudgeResult
+	// 自定义输入运行结果，不涉及提交记录
+	if envelope.Type == MessageTypeRunResult {
+		var result RunResult
 		if err := json.Unmarshal(message.Value, &result); err != nil {
-			log.Printf("Error unmarshaling judge result: %v", err)
-			session.MarkMessage(message, "")
-			continue
+			return &permanentError{fmt.Errorf("unmarshal run result: %w", err)}
 		}
+		saveRun(&result)
+		return nil
+	}
 
-		// 处理判题结果
-		if err := c.ProcessJudgeResult(&result); err != nil {
-			log.Printf("Error processing judge result: %v", err)
-			session.MarkMessage(message, "")
-			continue
+	// 进度消息
+	if envelope.Type == MessageTypeProgress {
+		var progress JudgeProgress
+		if err := json.Unmarshal(message.Value, &progress); err != nil {
+			return &permanentError{fmt.Errorf("unmarshal judge progress: %w", err)}
 		}
+		return c.ProcessJudgeProgress(&progress)
+	}
+
+	log.Printf("Received judge result: %s", string(message.Value))
 
-		// 标记消息已处理
-		session.MarkMessage(message, "")
+	// 解析判题结果
+	var result JudgeResult
+	if err := json.Unmarshal(message.Value, &result); err != nil {
+		return &permanentError{fmt.Errorf("unmarshal judge result: %w", err)}
 	}
 
-	return nil
+	// 处理判题结果
+	return c.ProcessJudgeResult(&result)
+}
+
+// DeadLetter 死信主题中的消息，保留原始消息与失败原因，便于排查后重新投递
+type DeadLetter struct {
+	Topic    string    `json:"topic"`
+	ID       string    `json:"id"` // 消息在队列中的位置，Kafka 为 partition/offset
+	Key      string    `json:"key"`
+	Value    string    `json:"value"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// sendToDeadLetter 将处理失败的消息发送到死信主题
+func (c *JudgeResultConsumer) sendToDeadLetter(ctx context.Context, message *queue.Message, cause error, attempts int) error {
+	value, err := json.Marshal(DeadLetter{
+		Topic:    message.Topic,
+		ID:       message.ID,
+		Key:      message.Key,
+		Value:    string(message.Value),
+		Error:    cause.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return JudgeQueue.Publish(ctx, config.GetConfig().Kafka.DeadLetterTopic, message.Key, value)
 }
 
 // ProcessJudgeResult 处理判题结果
-func (c *JudgeResultConsumer) ProcessJudgeResult(result *JudgeResult) error {
+func (c *JudgeResultConsumer) ProcessJudgeResult(result *JudgeResult) (err error) {
 	// 开启事务
 	tx := c.db.Begin()
 	if tx.Error != nil {
@@ -120,48 +240,217 @@ func (c *JudgeResultConsumer) ProcessJudgeResult(result *JudgeResult) error {
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
+			err = fmt.Errorf("panic while processing judge result: %v", r)
 		}
 	}()
 
-	// 更新提交记录
+	// 锁定提交记录，同一提交的结果串行处理
 	var submission models.Submission
-	if err := tx.First(&submission, result.SubmissionID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&submission, result.SubmissionID).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// 已出最终结果的提交忽略重复投递或重复评测产生的结果，保证每次评测只计一次
+	if !isPendingStatus(submission.Status) {
+		tx.Rollback()
+		log.Printf("Submission %d already finalized by judge run %q, skipping result of run %q",
+			submission.ID, submission.JudgeRunID, result.RunID)
+		return nil
+	}
+
+	// 只接受最近一次发送的判题任务的结果，超时重新发送或重新评测前的评测结果晚到时丢弃
+	current, err := currentJudgeRun(tx, &submission, result.RunID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !current {
+		tx.Rollback()
+		log.Printf("Submission %d is waiting for judge run %q, skipping stale result of run %q",
+			submission.ID, submission.JudgeRunID, result.RunID)
+		return nil
+	}
+
 	// 更新提交状态
 	submission.Status = result.Status
 	submission.RunTime = result.RunTime
 	submission.Memory = result.Memory
 	submission.ErrorMessage = result.ErrorMessage
+	submission.CompileLog = result.CompileLog
 	submission.JudgedAt = time.Now()
+	submission.JudgeRunID = result.RunID
+
+	score, err := submissionScore(tx, result)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	submission.Score = score
 
 	if err := tx.Save(&submission).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// 保存测试用例结果
-	for _, testCaseResult := range result.TestCases {
-		testCaseRes := models.TestCaseResult{
-			SubmissionID:   result.SubmissionID,
-			TestCaseID:     testCaseResult.TestCaseID,
-			Input:          testCaseResult.Input,
-			ExpectedOutput: testCaseResult.ExpectedOutput,
-			UserOutput:     testCaseResult.UserOutput,
-			Status:         testCaseResult.Status,
-			RunTime:        testCaseResult.RunTime,
-			Memory:         testCaseResult.Memory,
-			ErrorMessage:   testCaseResult.ErrorMessage,
+	// 保存测试用例结果，最终结果覆盖进度消息中已保存的结果，并删除最终结果中没有的用例
+	if err := upsertTestCaseResults(tx, result.SubmissionID, result.TestCases); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// 唯一索引覆盖已软删除的行，需物理删除，否则之后的结果会更新到已删除的行上
+	stale := tx.Unscoped().Where("submission_id = ?", result.SubmissionID)
+	if len(result.TestCases) > 0 {
+		ids := make([]uint, 0, len(result.TestCases))
+		for _, tc := range result.TestCases {
+			ids = append(ids, tc.TestCaseID)
 		}
+		stale = stale.Where("test_case_id NOT IN ?", ids)
+	}
+	if err := stale.Delete(&models.TestCaseResult{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 
-		if err := tx.Create(&testCaseRes).Error; err != nil {
-			tx.Rollback()
-			return err
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 该用户暂缓发送的判题任务可以发送了
+	notifyOutbox()
+
+	// 个人统计与比赛榜单随判题结果变化
+	cacheDelete(dashboardStatsKey(submission.UserID))
+	if submission.ContestID != 0 {
+		invalidateScoreboard(submission.ContestID)
+	}
+	publishSubmissionEvent(submissionSnapshot(&submission))
+	return nil
+}
+
+// currentJudgeRun 评测ID是否属于提交当前等待的评测。提交记录了评测ID时必须一致；
+// 旧版API服务发送的任务没有评测ID，此时只拒绝重新评测前已记录在历史中的评测
+func currentJudgeRun(tx *gorm.DB, submission *models.Submission, runID string) (bool, error) {
+	if submission.JudgeRunID != "" {
+		return runID == submission.JudgeRunID, nil
+	}
+	if runID == "" {
+		return true, nil
+	}
+	var count int64
+	if err := tx.Model(&models.SubmissionHistory{}).
+		Where("submission_id = ? AND judge_run_id = ?", submission.ID, runID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// ProcessJudgeProgress 处理判题进度：提交标记为评测中，保存已完成的用例结果，并推送给订阅该提交的客户端。
+// 重复投递的进度可以安全地再次处理，已出最终结果的提交与之前评测的进度被忽略
+func (c *JudgeResultConsumer) ProcessJudgeProgress(progress *JudgeProgress) error {
+	judging := false
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		// 与最终结果一样，只接受当前评测的进度
+		res := tx.Model(&models.Submission{}).
+			Where("id = ? AND status IN ?", progress.SubmissionID, []string{"pending", "judging"}).
+			Where("judge_run_id = '' OR judge_run_id = ?", progress.RunID).
+			Update("status", "judging")
+		if res.Error != nil {
+			return res.Error
 		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		judging = true
+
+		if progress.Stage == StageCaseResult && progress.TestCase != nil {
+			return upsertTestCaseResults(tx, progress.SubmissionID, []TestCaseResult{*progress.TestCase})
+		}
+		return nil
+	})
+	if err != nil || !judging {
+		return err
 	}
 
-	// 提交事务
-	return tx.Commit().Error
+	event := SubmissionEvent{
+		SubmissionID: progress.SubmissionID,
+		Status:       progress.Stage,
+		Case:         progress.Case,
+		Total:        progress.Total,
+	}
+	if progress.TestCase != nil {
+		event.CaseStatus = progress.TestCase.Status
+	}
+	publishSubmissionEvent(event)
+	return nil
+}
+
+// upsertTestCaseResults 按提交与用例插入或更新测试用例结果
+func upsertTestCaseResults(tx *gorm.DB, submissionID uint, results []TestCaseResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	rows := make([]models.TestCaseResult, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, models.TestCaseResult{
+			SubmissionID:   submissionID,
+			TestCaseID:     r.TestCaseID,
+			Input:          r.Input,
+			ExpectedOutput: r.ExpectedOutput,
+			UserOutput:     r.UserOutput,
+			Status:         r.Status,
+			RunTime:        r.RunTime,
+			Memory:         r.Memory,
+			ErrorMessage:   r.ErrorMessage,
+			Signal:         r.Signal,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "submission_id"}, {Name: "test_case_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"input", "expected_output", "user_output", "status", "run_time", "memory", "error_message", "signal", "updated_at",
+			"deleted_at", // 恢复之前被软删除的同一用例的结果
+		}),
+	}).Create(&rows).Error
+}
+
+// submissionScore 按子任务或测试用例权重计算得分。测试数据可能在评测后被替换，因此包含已删除的用例
+func submissionScore(tx *gorm.DB, result *JudgeResult) (float64, error) {
+	var subtasks []models.Subtask
+	if err := tx.Where("problem_id = ?", result.ProblemID).Order("sort_order, id").Find(&subtasks).Error; err != nil {
+		return 0, err
+	}
+	if len(subtasks) == 0 && result.Status == "accepted" {
+		return scoring.FullScore, nil
+	}
+	if len(result.TestCases) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, 0, len(result.TestCases))
+	for _, tc := range result.TestCases {
+		ids = append(ids, tc.TestCaseID)
+	}
+	var testCases []models.TestCase
+	if err := tx.Unscoped().Select("id, weight, subtask_id").Where("id IN ?", ids).Find(&testCases).Error; err != nil {
+		return 0, err
+	}
+	byID := make(map[uint]*models.TestCase, len(testCases))
+	for i := range testCases {
+		byID[testCases[i].ID] = &testCases[i]
+	}
+
+	cases := make([]scoring.Case, 0, len(result.TestCases))
+	for _, tc := range result.TestCases {
+		c := scoring.Case{Passed: tc.Status == "passed"}
+		if stored := byID[tc.TestCaseID]; stored != nil {
+			c.SubtaskID, c.Weight = stored.SubtaskID, stored.Weight
+		}
+		cases = append(cases, c)
+	}
+	score, _ := scoring.Score(subtasks, cases)
+	return score, nil
 }
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JudgeHeartbeat 判题机心跳消息结构
+type JudgeHeartbeat struct {
+	Name        string    `json:"name"`
+	Host        string    `json:"host"`
+	Languages   []string  `json:"languages"`
+	Concurrency int       `json:"concurrency"`
+	Running     int       `json:"running"` // 正在判题的任务数
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// InitJudgeHeartbeatConsumer 初始化判题机心跳消费者，维护判题机注册表
+func InitJudgeHeartbeatConsumer(db *gorm.DB) error {
+	if JudgeQueue == nil {
+		return errors.New("judge queue is not initialized")
+	}
+	cfg := config.GetConfig()
+	consumer := &JudgeHeartbeatConsumer{db: db}
+
+	go func() {
+		if err := JudgeQueue.Consume(context.Background(), "judge-heartbeat-group", []string{cfg.Kafka.HeartbeatTopic}, consumer.Handle); err != nil {
+			log.Printf("Error consuming judge heartbeats: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// JudgeHeartbeatConsumer 判题机心跳消费者结构
+type JudgeHeartbeatConsumer struct {
+	db *gorm.DB
+}
+
+// Handle 处理一条心跳消息。心跳会周期性重发，处理失败也无需重试
+func (c *JudgeHeartbeatConsumer) Handle(ctx context.Context, message *queue.Message) error {
+	var heartbeat JudgeHeartbeat
+	if err := json.Unmarshal(message.Value, &heartbeat); err != nil {
+		log.Printf("Error unmarshaling judge heartbeat: %v", err)
+	} else if err := c.ProcessHeartbeat(&heartbeat); err != nil {
+		log.Printf("Error processing judge heartbeat: %v", err)
+	}
+	return nil
+}
+
+// ProcessHeartbeat 根据心跳注册或更新判题机，状态由管理员控制，心跳不会修改
+func (c *JudgeHeartbeatConsumer) ProcessHeartbeat(heartbeat *JudgeHeartbeat) error {
+	if heartbeat.Name == "" {
+		return nil
+	}
+
+	judge := models.Judge{
+		Name:          heartbeat.Name,
+		Host:          heartbeat.Host,
+		Languages:     strings.Join(heartbeat.Languages, ","),
+		Concurrency:   heartbeat.Concurrency,
+		Running:       heartbeat.Running,
+		Status:        "active",
+		LastHeartbeat: heartbeat.SentAt,
+	}
+
+	// 按名称更新，忽略比已记录心跳更早的消息
+	return c.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"host", "languages", "concurrency", "running", "last_heartbeat", "updated_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "judges.last_heartbeat < excluded.last_heartbeat"},
+		}},
+	}).Create(&judge).Error
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// judgeOnline 最近三个心跳周期内有心跳的判题机视为在线
+func judgeOnline(judge *models.Judge, now time.Time) bool {
+	return now.Sub(judge.LastHeartbeat) <= 3*config.GetConfig().Judge.Heartbeat()
+}
+
+// GetJudges 获取判题机列表（管理员）
+func GetJudges(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var judges []models.Judge
+
+	if err := db.Order("name").Find(&judges).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取判题机列表失败"})
+		return
+	}
+
+	now := time.Now()
+	for i := range judges {
+		judges[i].Online = judgeOnline(&judges[i], now)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"judges": judges,
+	})
+}
+
+// DrainJudge 停止向判题机分配新任务，正在执行的任务继续完成（管理员）
+func DrainJudge(c *gin.Context) {
+	setJudgeStatus(c, "draining")
+}
+
+// DisableJudge 禁用判题机，立即停止接收新任务并中止正在执行的任务，中止的任务由其他判题机重新评测（管理员）
+func DisableJudge(c *gin.Context) {
+	setJudgeStatus(c, "disabled")
+}
+
+// EnableJudge 恢复判题机接收任务（管理员）
+func EnableJudge(c *gin.Context) {
+	setJudgeStatus(c, "active")
+}
+
+// setJudgeStatus 更新判题机状态，判题机通过数据库感知状态变化
+func setJudgeStatus(c *gin.Context, status string) {
+	judgeID := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+	var judge models.Judge
+
+	if err := db.First(&judge, judgeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "判题机不存在"})
+		return
+	}
+
+	if err := db.Model(&judge).Update("status", status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新判题机状态失败"})
+		return
+	}
+
+	judge.Online = judgeOnline(&judge, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"message": "判题机状态已更新",
+		"judge":   judge,
+	})
+}
+
+// DeleteJudge 从注册表移除判题机（管理员），仍在运行的判题机会在下次心跳时重新注册
+func DeleteJudge(c *gin.Context) {
+	judgeID := c.Param("id")
+	db := c.MustGet("db").(*gorm.DB)
+
+	result := db.Unscoped().Delete(&models.Judge{}, judgeID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除判题机失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "判题机不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "判题机已删除"})
+}
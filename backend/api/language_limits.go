@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend/common/language"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LanguageLimit 题目在某种语言下实际使用的时间限制（毫秒）与内存限制（MB）
+type LanguageLimit struct {
+	Language    string `json:"language"`
+	Name        string `json:"name"`
+	TimeLimit   int    `json:"time_limit"`
+	MemoryLimit int    `json:"memory_limit"`
+}
+
+// EffectiveLimits 题目在某种语言下的时间限制（毫秒）与内存限制（MB）。
+// override 中非零的限制直接使用，其余按题目限制乘以语言倍数计算
+func EffectiveLimits(problem *models.Problem, lang language.Language, override *models.ProblemLanguageLimit) (int, int) {
+	timeLimit := language.Scale(problem.TimeLimit, lang.TimeMultiplier)
+	memoryLimit := language.Scale(problem.MemoryLimit, lang.MemoryMultiplier)
+	if override != nil {
+		if override.TimeLimit > 0 {
+			timeLimit = override.TimeLimit
+		}
+		if override.MemoryLimit > 0 {
+			memoryLimit = override.MemoryLimit
+		}
+	}
+	return timeLimit, memoryLimit
+}
+
+// LanguageOverride 查询题目针对某种语言的限制，没有时返回 nil
+func LanguageOverride(db *gorm.DB, problemID uint, lang string) (*models.ProblemLanguageLimit, error) {
+	var override models.ProblemLanguageLimit
+	err := db.Where("problem_id = ? AND language = ?", problemID, lang).First(&override).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+// problemLimits 题目在各启用语言下的实际限制，按语言注册表的顺序排列
+func problemLimits(db *gorm.DB, problem *models.Problem) ([]LanguageLimit, error) {
+	var overrides []models.ProblemLanguageLimit
+	if err := db.Where("problem_id = ?", problem.ID).Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	byLanguage := make(map[string]*models.ProblemLanguageLimit, len(overrides))
+	for i := range overrides {
+		byLanguage[overrides[i].Language] = &overrides[i]
+	}
+
+	langs := language.List()
+	limits := make([]LanguageLimit, 0, len(langs))
+	for _, lang := range langs {
+		timeLimit, memoryLimit := EffectiveLimits(problem, lang, byLanguage[lang.ID])
+		limits = append(limits, LanguageLimit{
+			Language:    lang.ID,
+			Name:        lang.Name,
+			TimeLimit:   timeLimit,
+			MemoryLimit: memoryLimit,
+		})
+	}
+	return limits, nil
+}
+
+// languageLimitsRequest 设置题目语言限制的请求，替换题目原有的全部语言限制
+type languageLimitsRequest struct {
+	Limits []struct {
+		Language    string `json:"language"`
+		TimeLimit   int    `json:"time_limit"`
+		MemoryLimit int    `json:"memory_limit"`
+	} `json:"limits"`
+}
+
+// overrides 校验请求并转换为题目的语言限制
+func (r *languageLimitsRequest) overrides(problemID uint) ([]models.ProblemLanguageLimit, error) {
+	seen := make(map[string]bool, len(r.Limits))
+	overrides := make([]models.ProblemLanguageLimit, 0, len(r.Limits))
+	for _, l := range r.Limits {
+		if _, ok := language.Get(l.Language); !ok {
+			return nil, fmt.Errorf("unsupported language: %s", l.Language)
+		}
+		if seen[l.Language] {
+			return nil, fmt.Errorf("duplicate limits for language %s", l.Language)
+		}
+		seen[l.Language] = true
+		if l.TimeLimit < 0 || l.MemoryLimit < 0 {
+			return nil, errors.New("time_limit and memory_limit must not be negative")
+		}
+		if l.TimeLimit == 0 && l.MemoryLimit == 0 {
+			continue
+		}
+		overrides = append(overrides, models.ProblemLanguageLimit{
+			ProblemID:   problemID,
+			Language:    l.Language,
+			TimeLimit:   l.TimeLimit,
+			MemoryLimit: l.MemoryLimit,
+		})
+	}
+	return overrides, nil
+}
+
+// GetLanguageLimits 获取题目针对各语言设置的限制与实际使用的限制（管理员）
+func GetLanguageLimits(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var overrides []models.ProblemLanguageLimit
+	if err := db.Where("problem_id = ?", problem.ID).Order("language").Find(&overrides).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch language limits",
+		})
+		return
+	}
+	limits, err := problemLimits(db, problem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch language limits",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"overrides": overrides,
+		"limits":    limits,
+	})
+}
+
+// SetLanguageLimits 替换题目针对各语言设置的限制，时间与内存都为 0 的语言按倍数计算（管理员）
+func SetLanguageLimits(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var req languageLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	overrides, err := req.overrides(problem.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 语言限制有唯一索引，直接删除而非软删除
+		if err := tx.Unscoped().Where("problem_id = ?", problem.ID).Delete(&models.ProblemLanguageLimit{}).Error; err != nil {
+			return err
+		}
+		if len(overrides) == 0 {
+			return nil
+		}
+		return tx.Create(&overrides).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update language limits: " + err.Error(),
+		})
+		return
+	}
+
+	limits, err := problemLimits(db, problem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch language limits",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Language limits updated successfully",
+		"overrides": overrides,
+		"limits":    limits,
+		"status":    "success",
+	})
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+
+	"backend/common/language"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLanguages 获取可提交的语言列表，供代码编辑器选择语言
+func GetLanguages(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"languages": language.List(),
+		"status":    "success",
+	})
+}
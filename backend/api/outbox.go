@@ -0,0 +1,313 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"backend/common/language"
+	"backend/config"
+	"backend/models"
+	"backend/queue"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// outboxPollInterval 没有新消息通知时扫描发件箱的间隔
+	outboxPollInterval = time.Second
+	// outboxBatchSize 每次发送的最大消息数
+	outboxBatchSize = 100
+	// outboxMaxRetryDelay 发送失败后重试间隔的上限
+	outboxMaxRetryDelay = 5 * time.Minute
+	// outboxRetention 已发送消息的保留时间
+	outboxRetention = 7 * 24 * time.Hour
+	// outboxCleanupInterval 清理已发送消息的间隔
+	outboxCleanupInterval = time.Hour
+)
+
+// outboxNotify 有新消息写入发件箱时通知发送协程，缓冲为 1，多次通知合并为一次
+var outboxNotify = make(chan struct{}, 1)
+
+// notifyOutbox 在写入发件箱的事务提交后调用，让发送协程立即发送
+func notifyOutbox() {
+	select {
+	case outboxNotify <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueOutbox 在事务中写入一条待发送的消息，message 中的 Topic、Key 等字段由调用方填写
+func enqueueOutbox(tx *gorm.DB, message *models.OutboxMessage, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message.Payload = string(data)
+	message.NextAttemptAt = time.Now()
+	return tx.Create(message).Error
+}
+
+// 判题任务优先级，数值越小越优先，与 OutboxMessage.Priority 一致
+const (
+	priorityContest = iota // 比赛中的提交
+	priorityNormal         // 普通提交
+	priorityRejudge        // 重新评测
+)
+
+// judgePriority 提交的判题任务优先级，重新评测的比赛提交也按重新评测处理
+func judgePriority(submission *models.Submission) int {
+	switch {
+	case submission.RejudgedAt != nil:
+		return priorityRejudge
+	case submission.ContestID != 0:
+		return priorityContest
+	default:
+		return priorityNormal
+	}
+}
+
+// judgeTopic 判题任务优先级对应的主题
+func judgeTopic(priority int) string {
+	cfg := config.GetConfig()
+	switch priority {
+	case priorityContest:
+		return cfg.Kafka.ContestTopic
+	case priorityRejudge:
+		return cfg.Kafka.RejudgeTopic
+	default:
+		return cfg.Kafka.Topic
+	}
+}
+
+// enqueueJudgeTask 在事务中写入提交的判题任务，按优先级发送到对应的主题。
+// 每次发送生成新的评测ID并记录到提交中，只接受该次评测的结果，超时重新发送或重新评测前的评测结果被丢弃。
+// 比赛与普通提交受 judge.max_user_tasks 约束，同一用户已发送未评测完的任务达到上限时暂缓发送
+func enqueueJudgeTask(tx *gorm.DB, submission *models.Submission) error {
+	runID, err := newRunToken()
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(submission).Update("judge_run_id", runID).Error; err != nil {
+		return err
+	}
+	submission.JudgeRunID = runID
+
+	task := map[string]interface{}{
+		"run_id":        runID,
+		"submission_id": submission.ID,
+		"problem_id":    submission.ProblemID,
+		"user_id":       submission.UserID,
+		"language":      submission.Language,
+		"code":          submission.Code,
+		"submitted_at":  submission.SubmittedAt,
+	}
+	if err := addTaskLimits(tx, submission, task); err != nil {
+		return err
+	}
+	priority := judgePriority(submission)
+	message := &models.OutboxMessage{
+		Topic:        judgeTopic(priority),
+		Key:          strconv.FormatUint(uint64(submission.ID), 10),
+		Priority:     priority,
+		SubmissionID: submission.ID,
+	}
+	if priority != priorityRejudge {
+		message.UserID = submission.UserID
+	}
+
+	// 超时重新发送与重新评测复用提交已有的消息，每个提交最多一条判题任务
+	var existing models.OutboxMessage
+	err = tx.Where("submission_id = ?", submission.ID).Order("id DESC").Limit(1).Find(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing.ID == 0 {
+		return enqueueOutbox(tx, message, task)
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&existing).Updates(map[string]interface{}{
+		"topic":           message.Topic,
+		"key":             message.Key,
+		"payload":         string(data),
+		"priority":        message.Priority,
+		"user_id":         message.UserID,
+		"attempts":        0,
+		"last_error":      "",
+		"next_attempt_at": time.Now(),
+		"sent_at":         nil,
+	}).Error
+}
+
+// addTaskLimits 在判题任务中写入该语言实际使用的时间与内存限制，重新评测时使用题目当前的限制。
+// 题目已删除或语言已停用时不写入，由判题机报告原因
+func addTaskLimits(tx *gorm.DB, submission *models.Submission, task map[string]interface{}) error {
+	lang, ok := language.Get(submission.Language)
+	if !ok {
+		return nil
+	}
+	var problem models.Problem
+	err := tx.Select("id", "time_limit", "memory_limit").First(&problem, submission.ProblemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	override, err := LanguageOverride(tx, problem.ID, lang.ID)
+	if err != nil {
+		return err
+	}
+	task["time_limit"], task["memory_limit"] = EffectiveLimits(&problem, lang, override)
+	return nil
+}
+
+// InitOutboxDispatcher 启动发件箱发送协程。多个API实例可以同时运行，
+// 每条消息由锁定它的实例发送，至少发送一次，判题结果的处理是幂等的
+func InitOutboxDispatcher(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+		lastCleanup := time.Now()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-outboxNotify:
+			}
+
+			if JudgeQueue == nil {
+				continue
+			}
+			// 一批发满时可能还有积压，继续发送
+			for {
+				sent, err := dispatchOutbox(db, JudgeQueue)
+				if err != nil {
+					log.Printf("Error dispatching outbox: %v", err)
+				}
+				if err != nil || sent < outboxBatchSize {
+					break
+				}
+			}
+
+			if time.Since(lastCleanup) > outboxCleanupInterval {
+				lastCleanup = time.Now()
+				if err := db.Unscoped().Where("sent_at < ?", time.Now().Add(-outboxRetention)).
+					Delete(&models.OutboxMessage{}).Error; err != nil {
+					log.Printf("Error cleaning up outbox: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// userInFlightQuery 用户已发送但尚未评测完的判题任务，不包括消息自身对应的提交（超时重新发送的任务）
+const userInFlightQuery = `SELECT COUNT(DISTINCT o.submission_id) FROM outbox_messages o
+	JOIN submissions s ON s.id = o.submission_id
+	WHERE o.user_id = outbox_messages.user_id AND o.sent_at IS NOT NULL AND o.deleted_at IS NULL
+	AND s.status IN ('pending', 'judging') AND o.submission_id <> outbox_messages.submission_id`
+
+// dispatchOutbox 按优先级发送一批到期的消息，返回取出的消息数。
+// 使用 FOR UPDATE SKIP LOCKED 锁定消息，多个实例不会同时发送同一条消息；
+// 已达到 judge.max_user_tasks 的用户的判题任务暂不取出，等待其任务评测完成
+func dispatchOutbox(db *gorm.DB, q queue.Queue) (int, error) {
+	maxUserTasks := config.GetConfig().Judge.MaxUserTasks
+
+	var count int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND next_attempt_at <= ?", time.Now())
+		if maxUserTasks > 0 {
+			query = query.Where("(user_id = 0 OR ("+userInFlightQuery+") < ?)", maxUserTasks)
+		}
+
+		var messages []models.OutboxMessage
+		if err := query.Order("priority, id").Limit(outboxBatchSize).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		count = len(messages)
+
+		inFlight, err := userInFlight(tx, messages)
+		if err != nil {
+			return err
+		}
+
+		for i := range messages {
+			m := &messages[i]
+			// 同一批中同一用户的任务也不超过上限，其余留到下一批
+			if maxUserTasks > 0 && m.UserID != 0 && !inFlight[m.UserID][m.SubmissionID] {
+				if len(inFlight[m.UserID]) >= maxUserTasks {
+					continue
+				}
+				inFlight[m.UserID][m.SubmissionID] = true
+			}
+
+			updates := map[string]interface{}{}
+			if err := q.Publish(tx.Statement.Context, m.Topic, m.Key, []byte(m.Payload)); err != nil {
+				m.Attempts++
+				delay := outboxRetryDelay(m.Attempts)
+				log.Printf("Error sending outbox message %d (attempt %d), retrying in %v: %v", m.ID, m.Attempts, delay, err)
+				updates["attempts"] = m.Attempts
+				updates["last_error"] = err.Error()
+				updates["next_attempt_at"] = time.Now().Add(delay)
+			} else {
+				updates["sent_at"] = time.Now()
+			}
+			if err := tx.Model(m).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// userInFlight 批次中受约束的用户已发送但尚未评测完的提交
+func userInFlight(tx *gorm.DB, messages []models.OutboxMessage) (map[uint]map[uint]bool, error) {
+	inFlight := make(map[uint]map[uint]bool)
+	var userIDs []uint
+	for i := range messages {
+		if id := messages[i].UserID; id != 0 && inFlight[id] == nil {
+			inFlight[id] = make(map[uint]bool)
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return inFlight, nil
+	}
+
+	var rows []struct {
+		UserID       uint
+		SubmissionID uint
+	}
+	if err := tx.Table("outbox_messages o").
+		Select("DISTINCT o.user_id, o.submission_id").
+		Joins("JOIN submissions s ON s.id = o.submission_id").
+		Where("o.user_id IN ? AND o.sent_at IS NOT NULL AND o.deleted_at IS NULL", userIDs).
+		Where("s.status IN ?", []string{"pending", "judging"}).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		inFlight[row.UserID][row.SubmissionID] = true
+	}
+	return inFlight, nil
+}
+
+// outboxRetryDelay 第 attempts 次失败后的重试间隔，从 1 秒开始翻倍
+func outboxRetryDelay(attempts int) time.Duration {
+	if attempts > 20 {
+		return outboxMaxRetryDelay
+	}
+	delay := time.Second << (attempts - 1)
+	if delay > outboxMaxRetryDelay {
+		delay = outboxMaxRetryDelay
+	}
+	return delay
+}
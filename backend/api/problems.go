@@ -1,30 +1,44 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"backend/common/language"
 	"backend/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// GetProblems 获取所有问题列表
+// GetProblems 获取所有问题列表，尚未开始的比赛中的题目只对管理员可见
 func GetProblems(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var problems []models.Problem
-	if err := db.Order("id").Find(&problems).Error; err != nil {
+	query := db.Order("id")
+	if !isAdmin(c) {
+		query = query.Where("id NOT IN (?)", unreleasedProblemIDs(db, time.Now()))
+	}
+	if err := query.Find(&problems).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch problems",
 		})
 		return
 	}
+	if !isAdmin(c) {
+		for i := range problems {
+			problems[i].CheckerCode = ""
+			problems[i].InteractorCode = ""
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"problems": problems,
 	})
 }
 
-// GetProblem 获取单个问题详情
+// GetProblem 获取单个问题详情，尚未开始的比赛中的题目只对管理员可见
 func GetProblem(c *gin.Context) {
 	id := c.Param("id")
 	db := c.MustGet("db").(*gorm.DB)
@@ -35,8 +49,69 @@ func GetProblem(c *gin.Context) {
 		})
 		return
 	}
+	if !isAdmin(c) {
+		released, err := problemReleased(db, problem.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch problem",
+			})
+			return
+		}
+		if !released {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Failed to fetch problem",
+			})
+			return
+		}
+	}
+
+	// 附带示例测试用例，隐藏用例仅对管理员可见
+	examples := db.Where("problem_id = ? AND is_example = ?", problem.ID, true)
+	if !isAdmin(c) {
+		examples = examples.Where("is_hidden = ?", false)
+		problem.CheckerCode = ""
+		problem.InteractorCode = ""
+	}
+	if err := examples.Order("sort_order, id").Find(&problem.TestCases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch test cases",
+		})
+		return
+	}
+	// 较大的示例存放在文件存储中，返回其内容，存储路径不向普通用户展示
+	for i := range problem.TestCases {
+		tc := &problem.TestCases[i]
+		if err := loadTestCaseData(tc); err != nil {
+			log.Printf("Error loading example %d: %v", tc.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch test cases",
+			})
+			return
+		}
+		if !isAdmin(c) {
+			tc.InputFile = ""
+			tc.OutputFile = ""
+		}
+	}
+	if err := db.Where("problem_id = ?", problem.ID).Order("sort_order, id").Find(&problem.Subtasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch subtasks",
+		})
+		return
+	}
+
+	// 各语言实际使用的时间与内存限制
+	limits, err := problemLimits(db, &problem)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch language limits",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"problem": problem,
+		"limits":  limits,
 	})
 }
 
@@ -63,6 +138,13 @@ func CreateProblem(c *gin.Context) {
 
 	problem.CreatedBy = userID
 
+	if err := validateJudgeConfig(&problem); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	if err := db.Create(&problem).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create problem: " + err.Error(),
@@ -107,6 +189,20 @@ func UpdateProblem(c *gin.Context) {
 	problem.TimeLimit = updateData.TimeLimit
 	problem.MemoryLimit = updateData.MemoryLimit
 	problem.Tags = updateData.Tags
+	problem.CheckerMode = updateData.CheckerMode
+	problem.FloatPrecision = updateData.FloatPrecision
+	problem.CheckerLanguage = updateData.CheckerLanguage
+	problem.CheckerCode = updateData.CheckerCode
+	problem.JudgeMode = updateData.JudgeMode
+	problem.InteractorLanguage = updateData.InteractorLanguage
+	problem.InteractorCode = updateData.InteractorCode
+
+	if err := validateJudgeConfig(&problem); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	// 保存更新
 	if err := db.Save(&problem).Error; err != nil {
@@ -159,12 +255,40 @@ func DeleteProblem(c *gin.Context) {
 	})
 }
 
-// 添加测试用例
-func addTestCase(c *gin.Context) {
-	problemID := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "添加测试用例",
-		"problem_id": problemID,
-		"status":     "success",
-	})
+// validateJudgeConfig 校验题目的评测方式与检查模式，未指定时使用 standard 与 exact
+func validateJudgeConfig(problem *models.Problem) error {
+	switch problem.CheckerMode {
+	case "":
+		problem.CheckerMode = "exact"
+	case "exact", "whitespace":
+	case "float":
+		if problem.FloatPrecision < 0 {
+			return errors.New("float_precision must not be negative")
+		}
+	case "custom":
+		if !language.IsHelper(problem.CheckerLanguage) {
+			return errors.New("checker_language must be cpp or go")
+		}
+		if problem.CheckerCode == "" {
+			return errors.New("checker_code is required for a custom checker")
+		}
+	default:
+		return errors.New("checker_mode must be one of exact, whitespace, float, custom")
+	}
+
+	switch problem.JudgeMode {
+	case "":
+		problem.JudgeMode = "standard"
+	case "standard":
+	case "interactive":
+		if !language.IsHelper(problem.InteractorLanguage) {
+			return errors.New("interactor_language must be cpp or go")
+		}
+		if problem.InteractorCode == "" {
+			return errors.New("interactor_code is required for an interactive problem")
+		}
+	default:
+		return errors.New("judge_mode must be one of standard, interactive")
+	}
+	return nil
 }
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/common/database"
+	"backend/config"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// rateLimitTimeout 单次限流操作超时，Redis不可用时不限制提交
+	rateLimitTimeout = 200 * time.Millisecond
+	// rateLimitRetries 令牌桶被其他请求同时修改时的重试次数
+	rateLimitRetries = 3
+)
+
+// tokenBucket 保存在 Redis 中的令牌桶，状态为 tokens（剩余令牌数）与 ts（上次更新的毫秒时间戳）
+type tokenBucket struct {
+	key   string
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 容量
+}
+
+// newTokenBucket 按每分钟次数与容量创建令牌桶
+func newTokenBucket(key string, perMinute, burst int) tokenBucket {
+	return tokenBucket{key: key, rate: float64(perMinute) / 60, burst: float64(bucketBurst(burst, perMinute))}
+}
+
+// ttl 令牌桶补满所需的时间，之后状态与不存在时相同，可以过期删除
+func (b tokenBucket) ttl() time.Duration {
+	return time.Duration(math.Ceil(b.burst/b.rate*1000))*time.Millisecond + time.Second
+}
+
+// bucketState 令牌桶在 Redis 中的状态，found 为假时桶不存在，视为已满
+type bucketState struct {
+	tokens float64
+	ts     int64
+	found  bool
+}
+
+// refillTokens 按距上次更新经过的毫秒数补充令牌，不超过容量。不存在的桶是满的，时钟回拨时不补充
+func refillTokens(b tokenBucket, state bucketState, now int64) float64 {
+	if !state.found {
+		return b.burst
+	}
+	elapsed := math.Max(0, float64(now-state.ts))
+	return math.Min(b.burst, state.tokens+elapsed/1000*b.rate)
+}
+
+// takeAll 补充令牌后，所有桶都至少有一个令牌时各取一个并返回 0；否则都不取，返回需等待的时间。
+// 返回值 tokens 为各桶应保存的令牌数
+func takeAll(buckets []tokenBucket, states []bucketState, now int64) ([]float64, time.Duration) {
+	tokens := make([]float64, len(buckets))
+	var wait int64
+	for i, b := range buckets {
+		tokens[i] = refillTokens(b, states[i], now)
+		if tokens[i] < 1 {
+			if ms := int64(math.Ceil((1 - tokens[i]) / b.rate * 1000)); ms > wait {
+				wait = ms
+			}
+		}
+	}
+	if wait == 0 {
+		for i := range tokens {
+			tokens[i]--
+		}
+	}
+	return tokens, time.Duration(wait) * time.Millisecond
+}
+
+// submitRateLimit 按 judge.submit_rate 与 judge.problem_submit_rate 检查用户的提交频率，
+// 超出时返回需等待的时间。Redis不可用或出错时不限制
+func submitRateLimit(userID, problemID uint) time.Duration {
+	client := database.GetRedisClient()
+	if client == nil {
+		return 0
+	}
+	cfg := config.GetConfig().Judge
+
+	var buckets []tokenBucket
+	if cfg.SubmitRate > 0 {
+		buckets = append(buckets, newTokenBucket(fmt.Sprintf("ratelimit:submit:%d", userID), cfg.SubmitRate, cfg.SubmitBurst))
+	}
+	if cfg.ProblemSubmitRate > 0 {
+		buckets = append(buckets, newTokenBucket(fmt.Sprintf("ratelimit:submit:%d:%d", userID, problemID), cfg.ProblemSubmitRate, cfg.ProblemSubmitBurst))
+	}
+	return takeTokens(client, userID, buckets)
+}
+
+// runRateLimit 按 judge.run_rate 检查用户运行自定义输入的频率，与提交分开计数
+func runRateLimit(userID uint) time.Duration {
+	client := database.GetRedisClient()
+	cfg := config.GetConfig().Judge
+	if client == nil || cfg.RunRate <= 0 {
+		return 0
+	}
+	buckets := []tokenBucket{newTokenBucket(fmt.Sprintf("ratelimit:run:%d", userID), cfg.RunRate, cfg.RunBurst)}
+	return takeTokens(client, userID, buckets)
+}
+
+// takeTokens 从令牌桶中各取一个令牌，不足时返回需等待的时间，出错时不限制。
+// 使用 Redis 服务器时间，以 WATCH 乐观事务读写，多个API实例共享
+func takeTokens(client *redis.Client, userID uint, buckets []tokenBucket) time.Duration {
+	if len(buckets) == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitTimeout)
+	defer cancel()
+
+	keys := make([]string, len(buckets))
+	for i, b := range buckets {
+		keys[i] = b.key
+	}
+
+	var wait time.Duration
+	take := func(tx *redis.Tx) error {
+		serverTime, err := tx.Time(ctx).Result()
+		if err != nil {
+			return err
+		}
+		now := serverTime.UnixMilli()
+
+		states := make([]bucketState, len(buckets))
+		for i, b := range buckets {
+			values, err := tx.HMGet(ctx, b.key, "tokens", "ts").Result()
+			if err != nil {
+				return err
+			}
+			states[i] = parseBucketState(values)
+		}
+
+		var tokens []float64
+		tokens, wait = takeAll(buckets, states, now)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, b := range buckets {
+				pipe.HSet(ctx, b.key, "tokens", strconv.FormatFloat(tokens[i], 'f', -1, 64), "ts", now)
+				pipe.PExpire(ctx, b.key, b.ttl())
+			}
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < rateLimitRetries; attempt++ {
+		err := client.Watch(ctx, take, keys...)
+		if err == nil {
+			return wait
+		}
+		if err != redis.TxFailedErr {
+			log.Printf("Error checking rate limit for user %d: %v", userID, err)
+			return 0
+		}
+	}
+	log.Printf("Error checking rate limit for user %d: too many concurrent updates", userID)
+	return 0
+}
+
+// parseBucketState 解析 HMGET tokens ts 的结果，字段缺失或无法解析时视为不存在
+func parseBucketState(values []interface{}) bucketState {
+	if len(values) != 2 {
+		return bucketState{}
+	}
+	tokens, ok1 := values[0].(string)
+	ts, ok2 := values[1].(string)
+	if !ok1 || !ok2 {
+		return bucketState{}
+	}
+	n, err1 := strconv.ParseFloat(tokens, 64)
+	t, err2 := strconv.ParseInt(ts, 10, 64)
+	if err1 != nil || err2 != nil {
+		return bucketState{}
+	}
+	return bucketState{tokens: n, ts: t, found: true}
+}
+
+// bucketBurst 令牌桶容量，未配置时等于每分钟的提交次数
+func bucketBurst(burst, rate int) int {
+	if burst <= 0 {
+		return rate
+	}
+	return burst
+}
+
+// normalizeCode 去重前规范化代码：去掉 UTF-8 BOM，统一换行符为 \n，去掉行尾空白与首尾空行，
+// 只有这些差异的代码视为相同。行首缩进（例如 Python）保持不变
+func normalizeCode(code string) string {
+	code = strings.TrimPrefix(code, "\uFEFF")
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+	code = strings.ReplaceAll(code, "\r", "\n")
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// duplicateKey 同一用户向同一题目提交相同语言与代码时的去重键，代码按 normalizeCode 规范化
+func duplicateKey(submission *models.Submission) string {
+	sum := sha256.Sum256([]byte(submission.Language + "\x00" + normalizeCode(submission.Code)))
+	return fmt.Sprintf("submit:dup:%d:%d:%s", submission.UserID, submission.ProblemID, hex.EncodeToString(sum[:]))
+}
+
+// markSubmission 记录本次提交的代码，judge.duplicate_window 秒内已提交过相同代码时返回剩余的等待时间。
+// Redis不可用或出错时不检查
+func markSubmission(submission *models.Submission) time.Duration {
+	client := database.GetRedisClient()
+	window := config.GetConfig().Judge.DuplicateWindow
+	if client == nil || window <= 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rateLimitTimeout)
+	defer cancel()
+
+	key := duplicateKey(submission)
+	ok, err := client.SetNX(ctx, key, submission.UserID, time.Duration(window)*time.Second).Result()
+	if err != nil {
+		log.Printf("Error checking duplicate submission for user %d: %v", submission.UserID, err)
+		return 0
+	}
+	if ok {
+		return 0
+	}
+
+	ttl, err := client.PTTL(ctx, key).Result()
+	if err != nil || ttl <= 0 {
+		return time.Second
+	}
+	return ttl
+}
+
+// unmarkSubmission 提交未被接受时删除去重记录，允许立即重新提交
+func unmarkSubmission(submission *models.Submission) {
+	if config.GetConfig().Judge.DuplicateWindow > 0 {
+		cacheDelete(duplicateKey(submission))
+	}
+}
+
+// tooManyRequests 返回 429 与 Retry-After（秒，向上取整）
+func tooManyRequests(c *gin.Context, message string, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
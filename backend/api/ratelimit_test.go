@@ -0,0 +1,190 @@
+package api
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+func TestRefillTokens(t *testing.T) {
+	// 每分钟 6 次，即每秒 0.1 个令牌，容量 3
+	bucket := newTokenBucket("k", 6, 3)
+
+	tests := []struct {
+		name  string
+		state bucketState
+		now   int64
+		want  float64
+	}{
+		{name: "missing bucket is full", state: bucketState{}, now: 1000, want: 3},
+		{name: "no time elapsed", state: bucketState{tokens: 0.5, ts: 1000, found: true}, now: 1000, want: 0.5},
+		{name: "refills by elapsed time", state: bucketState{tokens: 0, ts: 1000, found: true}, now: 6000, want: 0.5},
+		{name: "fractional refill", state: bucketState{tokens: 1.25, ts: 0, found: true}, now: 2500, want: 1.5},
+		{name: "capped at burst", state: bucketState{tokens: 2, ts: 0, found: true}, now: 60000, want: 3},
+		{name: "clock going backwards does not refill", state: bucketState{tokens: 1, ts: 5000, found: true}, now: 1000, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refillTokens(bucket, tt.state, tt.now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("refillTokens = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTakeAll(t *testing.T) {
+	user := newTokenBucket("user", 6, 3)        // 每秒 0.1 个令牌
+	problem := newTokenBucket("problem", 60, 0) // 每秒 1 个令牌，容量默认等于每分钟次数
+
+	tests := []struct {
+		name       string
+		buckets    []tokenBucket
+		states     []bucketState
+		now        int64
+		wantTokens []float64
+		wantWait   time.Duration
+	}{
+		{
+			name:       "first request takes from full buckets",
+			buckets:    []tokenBucket{user, problem},
+			states:     []bucketState{{}, {}},
+			now:        0,
+			wantTokens: []float64{2, 59},
+		},
+		{
+			name:       "exactly one token left",
+			buckets:    []tokenBucket{user},
+			states:     []bucketState{{tokens: 1, ts: 0, found: true}},
+			now:        0,
+			wantTokens: []float64{0},
+		},
+		{
+			name:       "empty bucket waits for the next token",
+			buckets:    []tokenBucket{user},
+			states:     []bucketState{{tokens: 0.25, ts: 0, found: true}},
+			now:        0,
+			wantTokens: []float64{0.25},
+			wantWait:   7500 * time.Millisecond,
+		},
+		{
+			name:       "wait is rounded up to a millisecond",
+			buckets:    []tokenBucket{problem},
+			states:     []bucketState{{tokens: 0.9995, ts: 0, found: true}},
+			now:        0,
+			wantTokens: []float64{0.9995},
+			wantWait:   time.Millisecond,
+		},
+		{
+			name:       "one empty bucket blocks the others",
+			buckets:    []tokenBucket{user, problem},
+			states:     []bucketState{{tokens: 0, ts: 0, found: true}, {tokens: 10, ts: 0, found: true}},
+			now:        5000,
+			wantTokens: []float64{0.5, 15},
+			wantWait:   5 * time.Second,
+		},
+		{
+			name:       "longest wait wins",
+			buckets:    []tokenBucket{user, problem},
+			states:     []bucketState{{tokens: 0.9, ts: 0, found: true}, {tokens: 0, ts: 0, found: true}},
+			now:        0,
+			wantTokens: []float64{0.9, 0},
+			wantWait:   time.Second,
+		},
+		{
+			name:       "refill makes a token available",
+			buckets:    []tokenBucket{user},
+			states:     []bucketState{{tokens: 0, ts: 0, found: true}},
+			now:        10000,
+			wantTokens: []float64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, wait := takeAll(tt.buckets, tt.states, tt.now)
+			if wait != tt.wantWait {
+				t.Errorf("wait = %v, want %v", wait, tt.wantWait)
+			}
+			for i := range tokens {
+				if math.Abs(tokens[i]-tt.wantTokens[i]) > 1e-9 {
+					t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseBucketState(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   bucketState
+	}{
+		{name: "stored state", values: []interface{}{"1.5", "1700000000000"}, want: bucketState{tokens: 1.5, ts: 1700000000000, found: true}},
+		{name: "missing key", values: []interface{}{nil, nil}, want: bucketState{}},
+		{name: "garbage", values: []interface{}{"x", "1"}, want: bucketState{}},
+		{name: "wrong length", values: []interface{}{"1"}, want: bucketState{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBucketState(tt.values); got != tt.want {
+				t.Errorf("parseBucketState = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "unchanged", code: "int main() {\n\treturn 0;\n}", want: "int main() {\n\treturn 0;\n}"},
+		{name: "crlf", code: "a\r\nb\r\n", want: "a\nb"},
+		{name: "bare cr", code: "a\rb", want: "a\nb"},
+		{name: "trailing whitespace", code: "a  \t\nb \n", want: "a\nb"},
+		{name: "surrounding blank lines", code: "\n\n a\n\n\n", want: " a"},
+		{name: "bom", code: "\uFEFFprint(1)\n", want: "print(1)"},
+		{name: "indentation is kept", code: "if x:\n    y()\n", want: "if x:\n    y()"},
+		{name: "inner blank lines are kept", code: "a\n\n\nb", want: "a\n\n\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCode(tt.code); got != tt.want {
+				t.Errorf("normalizeCode(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateKey(t *testing.T) {
+	base := models.Submission{UserID: 1, ProblemID: 2, Language: "python", Code: "print(1)\n"}
+	key := duplicateKey(&base)
+
+	same := base
+	same.Code = "\uFEFFprint(1)   \r\n\r\n"
+	if duplicateKey(&same) != key {
+		t.Error("whitespace-only changes must produce the same key")
+	}
+
+	for name, modify := range map[string]func(s *models.Submission){
+		"code":     func(s *models.Submission) { s.Code = "print(2)\n" },
+		"indent":   func(s *models.Submission) { s.Code = "  print(1)\n" },
+		"language": func(s *models.Submission) { s.Language = "python2" },
+		"user":     func(s *models.Submission) { s.UserID = 3 },
+		"problem":  func(s *models.Submission) { s.ProblemID = 3 },
+	} {
+		other := base
+		modify(&other)
+		if duplicateKey(&other) == key {
+			t.Errorf("different %s must produce a different key", name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reaperBatchSize 每次扫描处理的最大提交数
+const reaperBatchSize = 100
+
+// reaperMetrics 超时提交回收的统计，通过 /api/admin/metrics 查看
+var reaperMetrics = expvar.NewMap("submission_reaper")
+
+// InitSubmissionReaper 启动超时提交回收协程：等待中或评测中的提交超过期限后重新发送判题任务，
+// 超过重新评测次数上限后标记为 system_error。多个API实例可以同时运行
+func InitSubmissionReaper(db *gorm.DB) {
+	cfg := config.GetConfig()
+	if cfg.Reaper.Interval <= 0 || cfg.Reaper.Deadline <= 0 {
+		log.Printf("Submission reaper is disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.Reaper.Interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := reapSubmissions(db, &cfg.Reaper, time.Now()); err != nil {
+				log.Printf("Error reaping stuck submissions: %v", err)
+				reaperMetrics.Add("errors", 1)
+			}
+		}
+	}()
+}
+
+// activeOutboxQuery 提交的判题任务尚未发送（受 judge.max_user_tasks 限制或排在高优先级任务之后），
+// 或发送后未超过期限。这样的提交还未开始评测或仍在期限内，不回收
+const activeOutboxQuery = `SELECT 1 FROM outbox_messages o
+	WHERE o.submission_id = submissions.id AND o.deleted_at IS NULL
+	AND (o.sent_at IS NULL OR o.sent_at >= ?::timestamptz - ? * INTERVAL '1 second')`
+
+// reapSubmissions 处理一批超时的提交。期限从判题任务发送到消息队列时开始计算，尚未发送的任务不计时；
+// 没有发件箱消息的提交（旧版本写入或消息已清理）从提交时间（重新评测的提交为重新评测时间）开始计算
+func reapSubmissions(db *gorm.DB, cfg *config.ReaperConfig, now time.Time) error {
+	var requeued, failed []models.Submission
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var stuck []models.Submission
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ?", []string{"pending", "judging"}).
+			Where("COALESCE(rejudged_at, submitted_at) < ?::timestamptz - ? * INTERVAL '1 second'", now, cfg.Deadline).
+			Where("NOT EXISTS ("+activeOutboxQuery+")", now, cfg.Deadline).
+			Order("id").Limit(reaperBatchSize).
+			Find(&stuck).Error; err != nil {
+			return err
+		}
+
+		for i := range stuck {
+			s := &stuck[i]
+			if s.RequeueCount < cfg.MaxRequeues {
+				s.RequeueCount++
+				s.Status = "pending"
+				if err := tx.Model(s).Updates(map[string]interface{}{
+					"status":        s.Status,
+					"requeue_count": s.RequeueCount,
+				}).Error; err != nil {
+					return err
+				}
+				// 重置提交原有的发件箱消息，重新发送时仍受用户并发上限与优先级约束
+				if err := enqueueJudgeTask(tx, s); err != nil {
+					return err
+				}
+				requeued = append(requeued, *s)
+				continue
+			}
+
+			s.Status = "system_error"
+			s.ErrorMessage = fmt.Sprintf("Judging did not finish within %d seconds of dispatch after %d requeues",
+				cfg.Deadline, s.RequeueCount)
+			s.JudgedAt = now
+			if err := tx.Model(s).Updates(map[string]interface{}{
+				"status":        s.Status,
+				"error_message": s.ErrorMessage,
+				"judged_at":     s.JudgedAt,
+			}).Error; err != nil {
+				return err
+			}
+			failed = append(failed, *s)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	reaperMetrics.Add("runs", 1)
+	reaperMetrics.Add("requeued", int64(len(requeued)))
+	reaperMetrics.Add("failed", int64(len(failed)))
+
+	if len(requeued) > 0 || len(failed) > 0 {
+		notifyOutbox()
+	}
+	if len(requeued) > 0 {
+		log.Printf("Requeued %d stuck submissions", len(requeued))
+	}
+	for i := range failed {
+		s := &failed[i]
+		log.Printf("Submission %d marked as system_error: %s", s.ID, s.ErrorMessage)
+		cacheDelete(dashboardStatsKey(s.UserID))
+		if s.ContestID != 0 {
+			invalidateScoreboard(s.ContestID)
+		}
+		publishSubmissionEvent(submissionSnapshot(s))
+	}
+	return nil
+}
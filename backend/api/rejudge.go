@@ -0,0 +1,327 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRejudgeSubmissions 单次重新评测的提交数上限
+const maxRejudgeSubmissions = 5000
+
+var (
+	errTooManyRejudges  = errors.New("too many submissions to rejudge")
+	errNothingToRejudge = errors.New("no submissions to rejudge")
+)
+
+// rejudgeRequest 按题目或用户重新评测时的筛选条件
+type rejudgeRequest struct {
+	OnlyAccepted bool       `json:"only_accepted"`
+	From         *time.Time `json:"from"` // RFC3339
+	To           *time.Time `json:"to"`
+}
+
+// RejudgeProgress 重新评测批次的进度，按提交的当前状态统计
+type RejudgeProgress struct {
+	Total    int64 `json:"total"`
+	Pending  int64 `json:"pending"`
+	Judging  int64 `json:"judging"`
+	Finished int64 `json:"finished"`
+	Changed  int64 `json:"changed"` // 已完成且结果或得分与重新评测前不同
+}
+
+// RejudgeSubmission 重新评测单个提交
+func RejudgeSubmission(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var submission models.Submission
+	if err := db.First(&submission, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	batch := models.RejudgeBatch{Scope: "submission", SubmissionID: submission.ID}
+	createRejudge(c, &batch, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ?", submission.ID)
+	})
+}
+
+// RejudgeProblem 重新评测题目的提交，可只评测通过的提交或限定提交时间
+func RejudgeProblem(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var problem models.Problem
+	if err := db.First(&problem, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Problem not found"})
+		return
+	}
+
+	var req rejudgeRequest
+	if !bindRejudgeRequest(c, &req) {
+		return
+	}
+
+	batch := models.RejudgeBatch{Scope: "problem", ProblemID: problem.ID}
+	createRejudge(c, &batch, func(tx *gorm.DB) *gorm.DB {
+		return req.apply(&batch, tx.Where("problem_id = ?", problem.ID))
+	})
+}
+
+// RejudgeUser 重新评测用户的提交，可只评测通过的提交或限定提交时间
+func RejudgeUser(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var user models.User
+	if err := db.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req rejudgeRequest
+	if !bindRejudgeRequest(c, &req) {
+		return
+	}
+
+	batch := models.RejudgeBatch{Scope: "user", UserID: user.ID}
+	createRejudge(c, &batch, func(tx *gorm.DB) *gorm.DB {
+		return req.apply(&batch, tx.Where("user_id = ?", user.ID))
+	})
+}
+
+// bindRejudgeRequest 解析可选的请求体，失败时写入响应并返回false
+func bindRejudgeRequest(c *gin.Context, req *rejudgeRequest) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if req.From != nil && req.To != nil && req.From.After(*req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return false
+	}
+	return true
+}
+
+// apply 记录筛选条件到批次并添加到查询
+func (r *rejudgeRequest) apply(batch *models.RejudgeBatch, query *gorm.DB) *gorm.DB {
+	batch.OnlyAccepted = r.OnlyAccepted
+	batch.From = r.From
+	batch.To = r.To
+
+	if r.OnlyAccepted {
+		query = query.Where("status = ?", "accepted")
+	}
+	if r.From != nil {
+		query = query.Where("submitted_at >= ?", *r.From)
+	}
+	if r.To != nil {
+		query = query.Where("submitted_at < ?", *r.To)
+	}
+	return query
+}
+
+// createRejudge 在一个事务中创建重新评测批次：保存提交原有的结果，清除测试用例结果，
+// 重置提交并以低优先级发送判题任务。scope 添加提交的筛选条件，等待中或评测中的提交不会重新评测
+func createRejudge(c *gin.Context, batch *models.RejudgeBatch, scope func(tx *gorm.DB) *gorm.DB) {
+	db := c.MustGet("db").(*gorm.DB)
+	batch.CreatedBy, _ = getCurrentUserID(c)
+
+	var submissions []models.Submission
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 锁定提交，避免与判题结果的处理同时修改
+		if err := scope(tx.Clauses(clause.Locking{Strength: "UPDATE"})).
+			Where("status NOT IN ?", []string{"pending", "judging"}).
+			Order("id").Limit(maxRejudgeSubmissions + 1).
+			Find(&submissions).Error; err != nil {
+			return err
+		}
+		if len(submissions) > maxRejudgeSubmissions {
+			return errTooManyRejudges
+		}
+		if len(submissions) == 0 {
+			return errNothingToRejudge
+		}
+
+		batch.Total = len(submissions)
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		ids := make([]uint, len(submissions))
+		histories := make([]models.SubmissionHistory, len(submissions))
+		for i := range submissions {
+			s := &submissions[i]
+			ids[i] = s.ID
+			histories[i] = models.SubmissionHistory{
+				SubmissionID:   s.ID,
+				RejudgeBatchID: batch.ID,
+				Status:         s.Status,
+				Score:          s.Score,
+				RunTime:        s.RunTime,
+				Memory:         s.Memory,
+				ErrorMessage:   s.ErrorMessage,
+				JudgeRunID:     s.JudgeRunID,
+				JudgedAt:       s.JudgedAt,
+			}
+		}
+		if err := tx.CreateInBatches(histories, 500).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("submission_id IN ?", ids).
+			Delete(&models.TestCaseResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Submission{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":        "pending",
+			"score":         0,
+			"run_time":      0,
+			"memory":        0,
+			"error_message": "",
+			"compile_log":   "",
+			"judge_run_id":  "",
+			"requeue_count": 0,
+			"rejudged_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		for i := range submissions {
+			s := &submissions[i]
+			s.Status = "pending"
+			s.Score, s.RunTime, s.Memory = 0, 0, 0
+			s.ErrorMessage = ""
+			s.CompileLog = ""
+			s.JudgeRunID = ""
+			s.RequeueCount = 0
+			s.RejudgedAt = &now
+			if err := enqueueJudgeTask(tx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	switch err {
+	case nil:
+	case errTooManyRejudges:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Too many submissions to rejudge at once, max " + strconv.Itoa(maxRejudgeSubmissions),
+		})
+		return
+	case errNothingToRejudge:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No finished submissions to rejudge"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rejudge"})
+		return
+	}
+
+	notifyOutbox()
+	invalidated := map[uint]bool{}
+	for i := range submissions {
+		s := &submissions[i]
+		cacheDelete(dashboardStatsKey(s.UserID))
+		if s.ContestID != 0 && !invalidated[s.ContestID] {
+			invalidated[s.ContestID] = true
+			invalidateScoreboard(s.ContestID)
+		}
+		publishSubmissionEvent(submissionSnapshot(s))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"batch":   batch,
+		"message": "Rejudge created",
+		"status":  "success",
+	})
+}
+
+// GetRejudges 获取重新评测批次列表 (带分页)
+func GetRejudges(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	db := c.MustGet("db").(*gorm.DB)
+
+	var batches []models.RejudgeBatch
+	var total int64
+	db.Model(&models.RejudgeBatch{}).Count(&total)
+	if err := db.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&batches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rejudges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rejudges":  batches,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"status":    "success",
+	})
+}
+
+// GetRejudge 获取重新评测批次的进度与每个提交前后的结果
+func GetRejudge(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var batch models.RejudgeBatch
+	if err := db.First(&batch, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rejudge not found"})
+		return
+	}
+
+	type rejudgeItem struct {
+		SubmissionID   uint    `json:"submission_id"`
+		PreviousStatus string  `json:"previous_status"`
+		PreviousScore  float64 `json:"previous_score"`
+		Status         string  `json:"status"`
+		Score          float64 `json:"score"`
+	}
+	var items []rejudgeItem
+	if err := db.Table("submission_histories h").
+		Select("h.submission_id, h.status AS previous_status, h.score AS previous_score, s.status, s.score").
+		Joins("JOIN submissions s ON s.id = h.submission_id").
+		Where("h.rejudge_batch_id = ? AND h.deleted_at IS NULL", batch.ID).
+		Order("h.submission_id").
+		Scan(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rejudge progress"})
+		return
+	}
+
+	progress := RejudgeProgress{Total: int64(batch.Total)}
+	for _, item := range items {
+		switch item.Status {
+		case "pending":
+			progress.Pending++
+		case "judging":
+			progress.Judging++
+		default:
+			progress.Finished++
+			if item.Status != item.PreviousStatus || item.Score != item.PreviousScore {
+				progress.Changed++
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch":       batch,
+		"progress":    progress,
+		"submissions": items,
+		"status":      "success",
+	})
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"backend/common/database"
+	"backend/common/language"
+	"backend/config"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxRunCodeSize 自定义输入运行的代码最大长度
+	maxRunCodeSize = 64 << 10
+	// maxRunInputSize 自定义输入的最大长度
+	maxRunInputSize = 256 << 10
+	// runResultTTL 运行结果的保留时间
+	runResultTTL = 10 * time.Minute
+	// runWait 请求等待运行结果的最长时间，超过后返回 202，由客户端轮询
+	runWait = 10 * time.Second
+	// runPollInterval 等待运行结果时的检查间隔
+	runPollInterval = 200 * time.Millisecond
+)
+
+// 运行方式
+const (
+	// RunModeCustom 以用户提供的输入运行
+	RunModeCustom = "custom"
+	// RunModeExamples 以题目的公开样例运行并判定，不计入提交
+	RunModeExamples = "examples"
+)
+
+// RunTask 自定义输入或样例运行任务，不创建提交记录，发送到 kafka.run_topic
+type RunTask struct {
+	RunID       string    `json:"run_id"`
+	UserID      uint      `json:"user_id"`
+	Mode        string    `json:"mode,omitempty"`       // custom（默认）或 examples
+	ProblemID   uint      `json:"problem_id,omitempty"` // examples 模式运行的题目
+	Language    string    `json:"language"`
+	Code        string    `json:"code"`
+	Input       string    `json:"input"`
+	TimeLimit   int       `json:"time_limit"`   // 毫秒
+	MemoryLimit int       `json:"memory_limit"` // MB
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RunResult 自定义输入运行结果，判题机以运行ID为键发送到结果主题
+type RunResult struct {
+	Type        string `json:"type"` // run_result
+	RunID       string `json:"run_id"`
+	UserID      uint   `json:"user_id"`
+	Mode        string `json:"mode,omitempty"`
+	Status      string `json:"status"` // pending, compilation_error, system_error；custom 模式为 finished 或运行错误类型，examples 模式与提交状态相同
+	Stdout      string `json:"stdout"`
+	Stderr      string `json:"stderr"`
+	CompileLog  string `json:"compile_log,omitempty"`
+	ExitCode    int    `json:"exit_code"`
+	Signal      string `json:"signal,omitempty"`
+	RunTime     int    `json:"run_time"`     // 毫秒
+	Memory      int    `json:"memory"`       // KB
+	TimeLimit   int    `json:"time_limit"`   // 毫秒
+	MemoryLimit int    `json:"memory_limit"` // MB
+
+	Cases []RunCaseResult `json:"cases,omitempty"` // examples 模式每个样例的结果
+}
+
+// RunCaseResult 样例运行中单个样例的结果。题目使用内置比较方式时，答案错误的样例附带与期望输出不同的行；
+// 自定义检查器与交互题不附带
+type RunCaseResult struct {
+	TestCaseResult
+	Diff []DiffLine `json:"diff,omitempty"`
+}
+
+// DiffLine 期望输出与用户输出中不同的一行，行号从 1 开始，一方没有该行时为空
+type DiffLine struct {
+	Line     int    `json:"line"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// localRuns 未启用 Redis 时的进程内运行结果，仅适用于单实例部署
+var localRuns = struct {
+	sync.Mutex
+	results map[string]localRun
+}{results: make(map[string]localRun)}
+
+type localRun struct {
+	result  RunResult
+	expires time.Time
+}
+
+// runKey 运行结果的缓存键
+func runKey(runID string) string {
+	return "run:" + runID
+}
+
+// saveRun 保存运行结果。启用 Redis 时所有 API 实例共享，否则保存在本进程
+func saveRun(result *RunResult) {
+	if database.GetRedisClient() != nil {
+		cacheSet(runKey(result.RunID), result, runResultTTL)
+		return
+	}
+
+	localRuns.Lock()
+	defer localRuns.Unlock()
+	now := time.Now()
+	for id, run := range localRuns.results {
+		if now.After(run.expires) {
+			delete(localRuns.results, id)
+		}
+	}
+	localRuns.results[result.RunID] = localRun{result: *result, expires: now.Add(runResultTTL)}
+}
+
+// loadRun 读取运行结果，不存在或已过期时返回false
+func loadRun(runID string) (*RunResult, bool) {
+	if database.GetRedisClient() != nil {
+		var result RunResult
+		if !cacheGet(runKey(runID), &result) {
+			return nil, false
+		}
+		return &result, true
+	}
+
+	localRuns.Lock()
+	defer localRuns.Unlock()
+	run, ok := localRuns.results[runID]
+	if !ok || time.Now().After(run.expires) {
+		return nil, false
+	}
+	result := run.result
+	return &result, true
+}
+
+// newRunToken 生成自定义输入运行的ID
+func newRunToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// runRequest 运行请求，指定题目时使用题目在该语言下的限制，examples 模式必须指定题目且忽略 input
+type runRequest struct {
+	Mode      string `json:"mode"`
+	Language  string `json:"language"`
+	Code      string `json:"code"`
+	Input     string `json:"input"`
+	ProblemID uint   `json:"problem_id"`
+}
+
+// runLimits 运行使用的时间限制（毫秒）与内存限制（MB）
+func runLimits(db *gorm.DB, problemID uint, lang language.Language) (int, int, error) {
+	if problemID == 0 {
+		cfg := config.GetConfig().Judge
+		return language.Scale(cfg.RunTimeLimit, lang.TimeMultiplier), language.Scale(cfg.RunMemoryLimit, lang.MemoryMultiplier), nil
+	}
+
+	var problem models.Problem
+	if err := db.First(&problem, problemID).Error; err != nil {
+		return 0, 0, err
+	}
+	override, err := LanguageOverride(db, problem.ID, lang.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	timeLimit, memoryLimit := EffectiveLimits(&problem, lang, override)
+	return timeLimit, memoryLimit, nil
+}
+
+// RunCode 以自定义输入或题目的公开样例运行代码，不创建提交记录，不影响通过统计。结果在 10 秒内返回时响应 200，
+// 否则响应 202，客户端通过 GET /run/:id 获取结果
+func RunCode(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var req runRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	lang, ok := language.Get(req.Language)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported language: " + req.Language,
+		})
+		return
+	}
+	if req.Code == "" || len(req.Code) > maxRunCodeSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Code must be between 1 byte and 64 KB",
+		})
+		return
+	}
+	if len(req.Input) > maxRunInputSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Input must not exceed 256 KB",
+		})
+		return
+	}
+
+	switch req.Mode {
+	case "":
+		req.Mode = RunModeCustom
+	case RunModeCustom:
+	case RunModeExamples:
+		if req.ProblemID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "problem_id is required to run examples",
+			})
+			return
+		}
+		req.Input = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "mode must be custom or examples",
+		})
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	// 尚未开始的比赛中的题目按不存在处理
+	if req.ProblemID != 0 && !isAdmin(c) {
+		released, err := problemReleased(db, req.ProblemID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to load problem limits",
+			})
+			return
+		}
+		if !released {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Problem not found",
+			})
+			return
+		}
+	}
+
+	timeLimit, memoryLimit, err := runLimits(db, req.ProblemID, lang)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Problem not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load problem limits",
+		})
+		return
+	}
+
+	if req.Mode == RunModeExamples {
+		var count int64
+		if err := db.Model(&models.TestCase{}).
+			Where("problem_id = ? AND is_example = ? AND is_hidden = ?", req.ProblemID, true, false).
+			Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch test cases",
+			})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Problem has no example test cases",
+			})
+			return
+		}
+	}
+
+	if wait := runRateLimit(userID); wait > 0 {
+		tooManyRequests(c, "Too many runs, please slow down", wait)
+		return
+	}
+
+	if JudgeQueue == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Judge queue is not available",
+		})
+		return
+	}
+
+	runID, err := newRunToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create run: " + err.Error(),
+		})
+		return
+	}
+	task := RunTask{
+		RunID:       runID,
+		UserID:      userID,
+		Mode:        req.Mode,
+		ProblemID:   req.ProblemID,
+		Language:    lang.ID,
+		Code:        req.Code,
+		Input:       req.Input,
+		TimeLimit:   timeLimit,
+		MemoryLimit: memoryLimit,
+		CreatedAt:   time.Now(),
+	}
+	value, err := json.Marshal(task)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create run: " + err.Error(),
+		})
+		return
+	}
+
+	// 先保存等待中的结果，判题机的结果可能在发送返回前到达
+	saveRun(&RunResult{
+		Type:        MessageTypeRunResult,
+		RunID:       runID,
+		UserID:      userID,
+		Mode:        req.Mode,
+		Status:      "pending",
+		TimeLimit:   timeLimit,
+		MemoryLimit: memoryLimit,
+	})
+	if err := JudgeQueue.Publish(c.Request.Context(), config.GetConfig().Kafka.RunTopic, runID, value); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to send run to judge: " + err.Error(),
+		})
+		return
+	}
+
+	result := waitRun(c.Request.Context(), runID)
+	status := http.StatusOK
+	if result == nil || result.Status == "pending" {
+		status = http.StatusAccepted
+	}
+	if result == nil {
+		result = &RunResult{Type: MessageTypeRunResult, RunID: runID, UserID: userID, Mode: req.Mode, Status: "pending"}
+	}
+	c.JSON(status, gin.H{
+		"run": result,
+	})
+}
+
+// waitRun 等待运行结果，超时或请求取消时返回当前的结果
+func waitRun(ctx context.Context, runID string) *RunResult {
+	ctx, cancel := context.WithTimeout(ctx, runWait)
+	defer cancel()
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+
+	for {
+		result, ok := loadRun(runID)
+		if ok && result.Status != "pending" {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			return result
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetRun 获取自定义输入运行的结果，仅运行者与管理员可见
+func GetRun(c *gin.Context) {
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	result, ok := loadRun(c.Param("id"))
+	if !ok || (result.UserID != userID && !isAdmin(c)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Run not found or expired",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"run": result,
+	})
+}
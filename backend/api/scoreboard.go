@@ -0,0 +1,259 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// icpcPenaltyMinutes ICPC 赛制每次错误提交的罚时
+	icpcPenaltyMinutes = 20
+	// scoreboardTTL 榜单缓存时间
+	scoreboardTTL = 10 * time.Second
+)
+
+// Scoreboard 比赛榜单
+type Scoreboard struct {
+	ContestID  uint                `json:"contest_id"`
+	RuleType   string              `json:"rule_type"`
+	Frozen     bool                `json:"frozen"`                // 封榜后的提交显示为等待中
+	FreezeTime *time.Time          `json:"freeze_time,omitempty"` // 封榜开始时间
+	Problems   []ScoreboardProblem `json:"problems"`
+	Rows       []ScoreboardRow     `json:"rows"`
+}
+
+// ScoreboardProblem 榜单中的题目
+type ScoreboardProblem struct {
+	Label     string `json:"label"`
+	ProblemID uint   `json:"problem_id"`
+	Title     string `json:"title"`
+}
+
+// ScoreboardRow 榜单中一名选手的成绩
+type ScoreboardRow struct {
+	Rank     int                        `json:"rank"`
+	UserID   uint                       `json:"user_id"`
+	Username string                     `json:"username"`
+	Solved   int                        `json:"solved"`
+	Penalty  int                        `json:"penalty"` // ICPC 罚时，分钟
+	Score    float64                    `json:"score"`   // OI 总分
+	Problems map[string]*ScoreboardCell `json:"problems"`
+}
+
+// ScoreboardCell 选手在单道题目上的成绩
+type ScoreboardCell struct {
+	Attempts int     `json:"attempts"` // ICPC 为首次通过前的错误提交数，OI 为已评测的提交数
+	Accepted bool    `json:"accepted"`
+	Time     int     `json:"time"`    // 首次通过距比赛开始的分钟数
+	Score    float64 `json:"score"`   // OI 最高得分
+	Pending  int     `json:"pending"` // 尚未出结果的提交数
+}
+
+// scoreboardSubmission 计算榜单所需的提交字段
+type scoreboardSubmission struct {
+	UserID      uint
+	ProblemID   uint
+	Status      string
+	Score       float64
+	SubmittedAt time.Time
+}
+
+// scoreboardCacheKey 榜单缓存键，frozen 区分选手看到的封榜榜单与真实榜单
+func scoreboardCacheKey(contestID uint, frozen bool) string {
+	if frozen {
+		return fmt.Sprintf("contest:scoreboard:%d:frozen", contestID)
+	}
+	return fmt.Sprintf("contest:scoreboard:%d", contestID)
+}
+
+// invalidateScoreboard 删除比赛的榜单缓存
+func invalidateScoreboard(contestID uint) {
+	cacheDelete(scoreboardCacheKey(contestID, false), scoreboardCacheKey(contestID, true))
+}
+
+// GetScoreboard 获取比赛榜单，封榜期间普通选手看到的封榜后提交为等待中，管理员始终看到真实榜单
+func GetScoreboard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	contest, ok := findContest(c, db)
+	if !ok {
+		return
+	}
+
+	frozen := !isAdmin(c) && contest.IsFrozen(time.Now())
+	var board Scoreboard
+	key := scoreboardCacheKey(contest.ID, frozen)
+	if !cacheGet(key, &board) {
+		if err := buildScoreboard(db, contest, frozen, &board); err != nil {
+			log.Printf("Error building scoreboard: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to build scoreboard",
+			})
+			return
+		}
+		cacheSet(key, &board, scoreboardTTL)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scoreboard": board,
+	})
+}
+
+// buildScoreboard 根据比赛期间的提交计算榜单，frozen 为真时封榜后的提交只计为等待中
+func buildScoreboard(db *gorm.DB, contest *models.Contest, frozen bool, board *Scoreboard) error {
+	board.ContestID = contest.ID
+	board.RuleType = contest.RuleType
+	board.Frozen = frozen
+	freezeTime, hasFreeze := contest.FreezeTime()
+	if hasFreeze {
+		board.FreezeTime = &freezeTime
+	}
+	board.Problems = []ScoreboardProblem{}
+	board.Rows = []ScoreboardRow{}
+
+	if err := db.Table("contest_problems").
+		Select("contest_problems.label, contest_problems.problem_id, problems.title").
+		Joins("JOIN problems ON problems.id = contest_problems.problem_id").
+		Where("contest_problems.contest_id = ? AND contest_problems.deleted_at IS NULL", contest.ID).
+		Order("contest_problems.label").
+		Scan(&board.Problems).Error; err != nil {
+		return err
+	}
+	labels := make(map[uint]string, len(board.Problems))
+	for _, p := range board.Problems {
+		labels[p.ProblemID] = p.Label
+	}
+
+	// 已报名的选手即使没有提交也出现在榜单中
+	var users []struct {
+		ID       uint
+		Username string
+	}
+	if err := db.Table("users").Select("users.id, users.username").
+		Where("users.id IN (?) OR users.id IN (?)",
+			db.Model(&models.ContestRegistration{}).Select("user_id").Where("contest_id = ?", contest.ID),
+			db.Model(&models.Submission{}).Select("user_id").Where("contest_id = ?", contest.ID)).
+		Scan(&users).Error; err != nil {
+		return err
+	}
+	rows := make(map[uint]*ScoreboardRow, len(users))
+	for _, u := range users {
+		rows[u.ID] = &ScoreboardRow{UserID: u.ID, Username: u.Username, Problems: map[string]*ScoreboardCell{}}
+	}
+
+	var submissions []scoreboardSubmission
+	if err := db.Model(&models.Submission{}).
+		Select("user_id, problem_id, status, score, submitted_at").
+		Where("contest_id = ? AND submitted_at >= ? AND submitted_at < ?", contest.ID, contest.StartTime, contest.EndTime).
+		Order("submitted_at, id").
+		Scan(&submissions).Error; err != nil {
+		return err
+	}
+
+	for _, s := range submissions {
+		row, label := rows[s.UserID], labels[s.ProblemID]
+		if row == nil || label == "" {
+			continue
+		}
+		cell := row.Problems[label]
+		if cell == nil {
+			cell = &ScoreboardCell{}
+			row.Problems[label] = cell
+		}
+		if frozen && !s.SubmittedAt.Before(freezeTime) {
+			if !cell.Accepted {
+				cell.Pending++
+			}
+			continue
+		}
+		if contest.RuleType == "oi" {
+			applyOISubmission(cell, &s)
+		} else {
+			applyICPCSubmission(cell, &s, contest.StartTime)
+		}
+	}
+
+	for _, row := range rows {
+		for _, cell := range row.Problems {
+			if cell.Accepted {
+				row.Solved++
+				if contest.RuleType != "oi" {
+					row.Penalty += cell.Time + icpcPenaltyMinutes*cell.Attempts
+				}
+			}
+			row.Score += cell.Score
+		}
+		board.Rows = append(board.Rows, *row)
+	}
+	rankScoreboard(board.Rows, contest.RuleType)
+	return nil
+}
+
+// isPendingStatus 提交是否尚未出结果
+func isPendingStatus(status string) bool {
+	return status == "pending" || status == "judging"
+}
+
+// applyICPCSubmission ICPC 赛制：首次通过计时，之前的错误提交计罚时，编译错误与系统错误不计
+func applyICPCSubmission(cell *ScoreboardCell, s *scoreboardSubmission, start time.Time) {
+	switch {
+	case cell.Accepted:
+	case isPendingStatus(s.Status):
+		cell.Pending++
+	case s.Status == "compilation_error" || s.Status == "system_error":
+	case s.Status == "accepted":
+		cell.Accepted = true
+		cell.Time = int(s.SubmittedAt.Sub(start).Minutes())
+	default:
+		cell.Attempts++
+	}
+}
+
+// applyOISubmission OI 赛制：取各次提交的最高分
+func applyOISubmission(cell *ScoreboardCell, s *scoreboardSubmission) {
+	if isPendingStatus(s.Status) {
+		cell.Pending++
+		return
+	}
+	cell.Attempts++
+	if s.Score > cell.Score {
+		cell.Score = s.Score
+	}
+	cell.Accepted = cell.Accepted || s.Status == "accepted"
+}
+
+// rankScoreboard 排序并计算名次，成绩相同的选手名次相同
+func rankScoreboard(rows []ScoreboardRow, ruleType string) {
+	better := func(a, b *ScoreboardRow) bool {
+		if ruleType == "oi" {
+			return a.Score > b.Score
+		}
+		if a.Solved != b.Solved {
+			return a.Solved > b.Solved
+		}
+		return a.Penalty < b.Penalty
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if better(&rows[i], &rows[j]) {
+			return true
+		}
+		if better(&rows[j], &rows[i]) {
+			return false
+		}
+		return rows[i].UserID < rows[j].UserID
+	})
+	for i := range rows {
+		if i > 0 && !better(&rows[i-1], &rows[i]) {
+			rows[i].Rank = rows[i-1].Rank
+		} else {
+			rows[i].Rank = i + 1
+		}
+	}
+}
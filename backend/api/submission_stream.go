@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// streamPingInterval 推送心跳并从数据库确认状态的间隔
+	streamPingInterval = 15 * time.Second
+	// streamMaxDuration 单个连接的最长时间，超过后由客户端重新连接
+	streamMaxDuration = 10 * time.Minute
+)
+
+// submissionSnapshot 以提交的当前状态构造事件
+func submissionSnapshot(submission *models.Submission) SubmissionEvent {
+	return SubmissionEvent{
+		SubmissionID: submission.ID,
+		Status:       submission.Status,
+		Score:        submission.Score,
+		RunTime:      submission.RunTime,
+		Memory:       submission.Memory,
+		Final:        !isPendingStatus(submission.Status),
+	}
+}
+
+// StreamSubmission 以 Server-Sent Events 推送提交的状态变化，首个事件为当前状态，评测结束后关闭连接
+func StreamSubmission(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var submission models.Submission
+	if err := db.First(&submission, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Submission not found",
+		})
+		return
+	}
+	if !checkSubmissionDetail(c, db, &submission) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	events, unsubscribe := subscribeSubmission(ctx, submission.ID)
+	defer unsubscribe()
+
+	// 订阅生效后再读取一次状态，避免错过订阅前已完成的评测
+	if err := db.First(&submission, submission.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return
+	}
+
+	// 封榜后的提交只推送是否评测结束，不推送进度与结果
+	frozen, err := submissionFrozen(c, db, &submission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return
+	}
+	send := func(event SubmissionEvent) {
+		if frozen {
+			event = maskSubmissionEvent(event)
+		}
+		c.SSEvent("status", event)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	snapshot := submissionSnapshot(&submission)
+	send(snapshot)
+	c.Writer.Flush()
+	if snapshot.Final {
+		return
+	}
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(streamMaxDuration)
+	defer deadline.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case event := <-events:
+			send(event)
+			return !event.Final
+		case <-ticker.C:
+			// 订阅者处理不及时会丢弃事件，定期从数据库确认是否已出结果
+			if err := db.First(&submission, submission.ID).Error; err == nil && !isPendingStatus(submission.Status) {
+				send(submissionSnapshot(&submission))
+				return false
+			}
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		}
+	})
+}
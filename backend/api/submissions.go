@@ -41,6 +41,23 @@ func GetSubmissions(c *gin.Context) {
 		})
 		return
 	}
+	list := make([]*models.Submission, len(submissions))
+	for i := range submissions {
+		restrictErrorMessage(c, &submissions[i])
+		list[i] = &submissions[i]
+	}
+	if _, err := maskFrozenSubmissions(c, db, list...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submissions",
+		})
+		return
+	}
+	if err := hideContestCode(c, db, list...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submissions",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"submissions": submissions,
@@ -65,6 +82,19 @@ func GetSubmission(c *gin.Context) {
 		})
 		return
 	}
+	restrictErrorMessage(c, &submission)
+	if _, err := maskFrozenSubmissions(c, db, &submission); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return
+	}
+	if err := hideContestCode(c, db, &submission); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"submission": submission,
@@ -86,6 +116,9 @@ func GetSubmissionResult(c *gin.Context) {
 		})
 		return
 	}
+	if !checkSubmissionDetail(c, db, &submission) {
+		return
+	}
 
 	// 获取题目信息
 	var problem models.Problem
@@ -96,26 +129,45 @@ func GetSubmissionResult(c *gin.Context) {
 		return
 	}
 
-	// 获取测试用例结果
-	var testCaseResults []models.TestCaseResult
-	if err := db.Where("submission_id = ?", id).Find(&testCaseResults).Error; err != nil {
+	// 封榜后的提交不展示评测结果
+	masked, err := maskFrozenSubmissions(c, db, &submission)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch test case results",
+			"error": "Failed to fetch submission",
 		})
 		return
 	}
 
-	// 如果没有测试用例结果，获取原始测试用例
-	if len(testCaseResults) == 0 {
-		var testCases []models.TestCase
-		if err := db.Where("problem_id = ?", submission.ProblemID).Find(&testCases).Error; err != nil {
+	// 获取测试用例结果
+	var testCaseResults []models.TestCaseResult
+	if !masked[submission.ID] {
+		if err := db.Where("submission_id = ?", id).Order("id").Find(&testCaseResults).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to fetch test cases",
+				"error": "Failed to fetch test case results",
 			})
 			return
 		}
+	}
+
+	var testCases []models.TestCase
+	if err := db.Where("problem_id = ?", submission.ProblemID).Order("sort_order, id").Find(&testCases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch test cases",
+		})
+		return
+	}
+
+	var subtasks []models.Subtask
+	if err := db.Where("problem_id = ?", submission.ProblemID).Order("sort_order, id").Find(&subtasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch subtasks",
+		})
+		return
+	}
+	scores := subtaskScores(subtasks, testCases, testCaseResults)
 
-		// 转换为测试用例结果格式
+	// 如果没有测试用例结果，使用原始测试用例
+	if len(testCaseResults) == 0 {
 		for _, tc := range testCases {
 			testCaseResults = append(testCaseResults, models.TestCaseResult{
 				TestCaseID:     tc.ID,
@@ -125,11 +177,34 @@ func GetSubmissionResult(c *gin.Context) {
 		}
 	}
 
+	// 隐藏测试用例的数据与检查器、交互器源码不向普通用户展示，只保留评测状态
+	if !isAdmin(c) {
+		problem.CheckerCode = ""
+		problem.InteractorCode = ""
+		visible, err := visibleTestCases(db, testCaseResults)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch test cases",
+			})
+			return
+		}
+		for i := range testCaseResults {
+			if !visible[testCaseResults[i].TestCaseID] {
+				testCaseResults[i].Input = ""
+				testCaseResults[i].ExpectedOutput = ""
+				testCaseResults[i].UserOutput = ""
+			}
+		}
+	}
+
+	restrictErrorMessage(c, &submission)
+
 	// 构造返回结果
 	result := map[string]interface{}{
 		"submission": submission,
 		"problem":    problem,
 		"test_cases": testCaseResults,
+		"subtasks":   scores,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -139,6 +214,46 @@ func GetSubmissionResult(c *gin.Context) {
 	})
 }
 
+// visibleTestCases 结果对应的用例中可以向普通用户展示数据的用例。
+// 用例删除后结果仍然保留，因此包括已软删除的用例；已不存在的用例按隐藏处理
+func visibleTestCases(db *gorm.DB, results []models.TestCaseResult) (map[uint]bool, error) {
+	ids := make([]uint, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.TestCaseID)
+	}
+	visible := make(map[uint]bool)
+	if len(ids) == 0 {
+		return visible, nil
+	}
+
+	var testCases []models.TestCase
+	if err := db.Unscoped().Select("id").Where("id IN ? AND is_hidden = ?", ids, false).Find(&testCases).Error; err != nil {
+		return nil, err
+	}
+	for _, tc := range testCases {
+		visible[tc.ID] = true
+	}
+	return visible, nil
+}
+
+// checkSubmissionDetail 检查当前用户能否查看提交的评测详情，不能时写入响应并返回false
+func checkSubmissionDetail(c *gin.Context, db *gorm.DB, submission *models.Submission) bool {
+	allowed, err := canViewSubmissionDetail(c, db, submission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch submission",
+		})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Contest submissions are only visible to their owner until the contest ends",
+		})
+		return false
+	}
+	return true
+}
+
 // RegisterSubmissionRoutes 注册提交相关路由
 func RegisterSubmissionRoutes(rg *gin.RouterGroup) {
 	submissions := rg.Group("/submissions")
@@ -146,5 +261,7 @@ func RegisterSubmissionRoutes(rg *gin.RouterGroup) {
 		submissions.GET("", GetSubmissions)
 		submissions.GET("/:id", GetSubmission)
 		submissions.GET("/:id/result", GetSubmissionResult)
+		submissions.GET("/:id/compile-log", GetCompileLog)
+		submissions.GET("/:id/stream", StreamSubmission)
 	}
 }
@@ -1,48 +1,33 @@
 package api
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 
+	"backend/common/language"
 	"backend/config"
 	"backend/models"
-	"github.com/IBM/sarama"
+	"backend/queue"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// KafkaProducer Kafka生产者实例
-var KafkaProducer sarama.SyncProducer
+// JudgeQueue 判题任务、判题结果与心跳使用的消息队列
+var JudgeQueue queue.Queue
 
-// InitKafka 初始化Kafka生产者
-func InitKafka(cfg *config.Config) error {
-	kafkaConfig := sarama.NewConfig()
-	kafkaConfig.Producer.Return.Successes = true
-	kafkaConfig.Producer.Return.Errors = true
-	kafkaConfig.ClientID = "oj-submit-service"
-
-	p, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, kafkaConfig)
+// InitQueue 按配置初始化消息队列，失败时退回进程内队列，由API服务内嵌的判题机评测
+func InitQueue(cfg *config.Config) error {
+	q, err := queue.New(cfg)
 	if err != nil {
-		log.Printf("警告: 初始化Kafka失败: %v", err)
-		log.Printf("系统将在无Kafka模式下运行")
-		KafkaProducer = nil
-		return nil
+		log.Printf("警告: 初始化%s队列失败: %v", cfg.Kafka.Driver, err)
+		log.Printf("系统将使用进程内队列运行")
+		q = queue.Memory(cfg)
 	}
 
-	KafkaProducer = p
-
-	// 启动事件处理协程
-	go handleKafkaEvents()
-
+	JudgeQueue = q
 	return nil
 }
 
-// handleKafkaEvents 处理Kafka事件
-func handleKafkaEvents() {
-	// SyncProducer不需要手动处理事件，这里留空或者可以移除
-}
-
 // Submit 提交代码并分配提交ID
 func Submit(db *gorm.DB, submission *models.Submission) error {
 	// 开启事务
@@ -59,50 +44,42 @@ func Submit(db *gorm.DB, submission *models.Submission) error {
 	// 创建提交记录
 	// 明确指定不使用已存在的ID值，让数据库自动生成主键
 	submission.ID = 0 // 强制清零ID以确保使用自增
+	// 新提交总是等待评测，评测结果只来自判题机
+	submission.Status = "pending"
+	submission.Score = 0
+	submission.RequeueCount = 0
+	submission.JudgeRunID = ""
+	submission.RejudgedAt = nil
 	if err := tx.Create(submission).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// 如果Kafka可用，则发送消息
-	if KafkaProducer != nil {
-		// 构造发送到Kafka的消息
-		submissionData := map[string]interface{}{
-			"submission_id": submission.ID,
-			"problem_id":    submission.ProblemID,
-			"user_id":       submission.UserID,
-			"language":      submission.Language,
-			"code":          submission.Code,
-			"submitted_at":  submission.SubmittedAt,
-		}
-
-		message, err := json.Marshal(submissionData)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
-
-		// 发送到Kafka
-		cfg := config.GetConfig()
+	// 判题任务写入发件箱，与提交记录同时提交，由后台任务发送到消息队列
+	if err := enqueueJudgeTask(tx, submission); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-		// 使用同步发送方式
-		msg := &sarama.ProducerMessage{
-			Topic: cfg.Kafka.Topic,
-			Value: sarama.StringEncoder(message),
-		}
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
 
-		_, _, err = KafkaProducer.SendMessage(msg)
-		if err != nil {
-			log.Printf("警告: 发送消息到Kafka失败: %v", err)
-			// 注意：即使Kafka发送失败，我们仍然提交数据库事务
-			// 因为提交记录已经保存到数据库中
-		}
-	} else {
-		log.Println("信息: Kafka不可用，跳过消息发送")
+	notifyOutbox()
+	cacheDelete(dashboardStatsKey(submission.UserID))
+	if submission.ContestID != 0 {
+		invalidateScoreboard(submission.ContestID)
 	}
+	return nil
+}
 
-	// 提交事务
-	return tx.Commit().Error
+// submitRequest 提交请求，只接受题目、比赛、语言与代码
+type submitRequest struct {
+	ProblemID uint   `json:"problem_id"`
+	ContestID uint   `json:"contest_id"` // 0 表示非比赛提交
+	Language  string `json:"language"`
+	Code      string `json:"code"`
 }
 
 // SubmitHandler 处理提交请求的HTTP处理函数
@@ -110,18 +87,28 @@ func SubmitHandler(c *gin.Context) {
 	// 从上下文中获取数据库实例
 	db := c.MustGet("db").(*gorm.DB)
 
-	// 解析请求体中的提交数据
-	var submission models.Submission
-	if err := c.ShouldBindJSON(&submission); err != nil {
+	// 解析请求体中的提交数据，评测状态与得分等字段只由服务端设置
+	var req submitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid request data",
 		})
 		return
 	}
 
-	// 设置默认状态
-	if submission.Status == "" {
-		submission.Status = "pending"
+	// 语言需在语言注册表中
+	if _, ok := language.Get(req.Language); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported language: " + req.Language,
+		})
+		return
+	}
+
+	submission := models.Submission{
+		ProblemID: req.ProblemID,
+		ContestID: req.ContestID,
+		Language:  req.Language,
+		Code:      req.Code,
 	}
 
 	// 从认证中间件中获取用户ID
@@ -136,8 +123,49 @@ func SubmitHandler(c *gin.Context) {
 	// 设置提交的用户ID
 	submission.UserID = userID
 
+	// 比赛内提交需要比赛进行中、已报名且题目属于该比赛
+	if submission.ContestID != 0 {
+		if status, err := checkContestSubmission(c, db, &submission); err != nil {
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// 尚未开始的比赛中的题目在比赛开始前不能在比赛外提交
+	if submission.ContestID == 0 && !isAdmin(c) {
+		released, err := problemReleased(db, submission.ProblemID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to submit code: " + err.Error(),
+			})
+			return
+		}
+		if !released {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "problem is part of a contest that has not started",
+			})
+			return
+		}
+	}
+
+	// 同一题目短时间内重复提交相同代码
+	if wait := markSubmission(&submission); wait > 0 {
+		tooManyRequests(c, "Identical code was already submitted to this problem, please wait before resubmitting", wait)
+		return
+	}
+
+	// 按用户与题目限制提交频率
+	if wait := submitRateLimit(submission.UserID, submission.ProblemID); wait > 0 {
+		unmarkSubmission(&submission)
+		tooManyRequests(c, "Too many submissions, please slow down", wait)
+		return
+	}
+
 	// 调用Submit函数处理提交
 	if err := Submit(db, &submission); err != nil {
+		unmarkSubmission(&submission)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to submit code: " + err.Error(),
 		})
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/common/scoring"
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxSubtasks 单个题目的最大子任务数
+const maxSubtasks = 50
+
+// subtaskRequest 创建或更新子任务的请求，未提供的字段在更新时保持不变
+type subtaskRequest struct {
+	Name        *string  `json:"name"`
+	Score       *float64 `json:"score"`
+	Aggregation *string  `json:"aggregation"`
+	DependsOn   *[]uint  `json:"depends_on"`
+	SortOrder   *int     `json:"sort_order"`
+}
+
+// apply 将请求中的字段写入子任务并校验，依赖关系需结合题目的其他子任务校验
+func (r *subtaskRequest) apply(s *models.Subtask) error {
+	if r.Name != nil {
+		s.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Score != nil {
+		s.Score = *r.Score
+	}
+	if r.Aggregation != nil {
+		s.Aggregation = *r.Aggregation
+	}
+	if r.DependsOn != nil {
+		ids := make([]string, 0, len(*r.DependsOn))
+		for _, id := range *r.DependsOn {
+			ids = append(ids, strconv.FormatUint(uint64(id), 10))
+		}
+		s.DependsOn = strings.Join(ids, ",")
+	}
+	if r.SortOrder != nil {
+		s.SortOrder = *r.SortOrder
+	}
+
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if s.Score < 0 {
+		return errors.New("score must not be negative")
+	}
+	if s.Aggregation != "min" && s.Aggregation != "sum" {
+		return errors.New("aggregation must be min or sum")
+	}
+	return nil
+}
+
+// validateSubtask 检查子任务名称在题目内唯一，依赖均属于同一题目且不存在循环依赖
+func validateSubtask(db *gorm.DB, s *models.Subtask) error {
+	var siblings []models.Subtask
+	if err := db.Where("problem_id = ? AND id <> ?", s.ProblemID, s.ID).Find(&siblings).Error; err != nil {
+		return err
+	}
+	graph := make(map[uint][]uint, len(siblings)+1)
+	for i := range siblings {
+		if siblings[i].Name == s.Name {
+			return fmt.Errorf("subtask %s already exists", s.Name)
+		}
+		graph[siblings[i].ID] = siblings[i].Dependencies()
+	}
+
+	for _, dep := range s.Dependencies() {
+		if s.ID != 0 && dep == s.ID {
+			return errors.New("a subtask cannot depend on itself")
+		}
+		if _, ok := graph[dep]; !ok {
+			return fmt.Errorf("subtask %d does not belong to this problem", dep)
+		}
+	}
+
+	// 从被依赖的子任务出发，若能回到当前子任务则存在循环
+	seen := make(map[uint]bool)
+	stack := s.Dependencies()
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if s.ID != 0 && id == s.ID {
+			return errors.New("subtask dependencies must not form a cycle")
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, graph[id]...)
+	}
+	return nil
+}
+
+// checkSubtask 检查测试用例引用的子任务属于该题目
+func checkSubtask(db *gorm.DB, problemID, subtaskID uint) error {
+	if subtaskID == 0 {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Subtask{}).Where("id = ? AND problem_id = ?", subtaskID, problemID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("subtask %d does not belong to this problem", subtaskID)
+	}
+	return nil
+}
+
+// findSubtask 查找属于题目的子任务，不存在时写入404响应
+func findSubtask(c *gin.Context, db *gorm.DB, problemID uint) (*models.Subtask, bool) {
+	var subtask models.Subtask
+	if err := db.Where("problem_id = ?", problemID).First(&subtask, c.Param("subtask_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Subtask not found",
+		})
+		return nil, false
+	}
+	return &subtask, true
+}
+
+// subtaskScores 根据测试用例结果计算各子任务得分，题目没有子任务时返回 nil
+func subtaskScores(subtasks []models.Subtask, testCases []models.TestCase, results []models.TestCaseResult) []scoring.SubtaskScore {
+	if len(subtasks) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*models.TestCase, len(testCases))
+	for i := range testCases {
+		byID[testCases[i].ID] = &testCases[i]
+	}
+	cases := make([]scoring.Case, 0, len(results))
+	for _, r := range results {
+		if tc := byID[r.TestCaseID]; tc != nil {
+			cases = append(cases, scoring.Case{SubtaskID: tc.SubtaskID, Weight: tc.Weight, Passed: r.Status == "passed"})
+		}
+	}
+	_, scores := scoring.Score(subtasks, cases)
+	return scores
+}
+
+// GetSubtasks 获取题目的子任务（管理员）
+func GetSubtasks(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var subtasks []models.Subtask
+	if err := db.Where("problem_id = ?", problem.ID).Order("sort_order, id").Find(&subtasks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch subtasks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subtasks": subtasks,
+	})
+}
+
+// CreateSubtask 为题目添加子任务（管理员）
+func CreateSubtask(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var req subtaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+
+	subtask := models.Subtask{ProblemID: problem.ID, Aggregation: "min"}
+	if err := req.apply(&subtask); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateSubtask(db, &subtask); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.Subtask{}).Where("problem_id = ?", problem.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count subtasks",
+		})
+		return
+	}
+	if count >= maxSubtasks {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A problem can have at most %d subtasks", maxSubtasks),
+		})
+		return
+	}
+
+	if err := db.Create(&subtask).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create subtask: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subtask created successfully",
+		"subtask": subtask,
+		"status":  "success",
+	})
+}
+
+// UpdateSubtask 更新子任务（管理员）
+func UpdateSubtask(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+	subtask, ok := findSubtask(c, db, problem.ID)
+	if !ok {
+		return
+	}
+
+	var req subtaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	if err := req.apply(subtask); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateSubtask(db, subtask); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := db.Save(subtask).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update subtask: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subtask updated successfully",
+		"subtask": subtask,
+		"status":  "success",
+	})
+}
+
+// DeleteSubtask 删除子任务，其测试用例不再属于任何子任务（管理员）
+func DeleteSubtask(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+	subtask, ok := findSubtask(c, db, problem.ID)
+	if !ok {
+		return
+	}
+
+	// 仍被其他子任务依赖时不允许删除
+	var siblings []models.Subtask
+	if err := db.Where("problem_id = ? AND id <> ?", problem.ID, subtask.ID).Find(&siblings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch subtasks",
+		})
+		return
+	}
+	for i := range siblings {
+		for _, dep := range siblings[i].Dependencies() {
+			if dep == subtask.ID {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("Subtask %s depends on this subtask", siblings[i].Name),
+				})
+				return
+			}
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TestCase{}).Where("subtask_id = ?", subtask.ID).Update("subtask_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(subtask).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete subtask: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subtask deleted successfully",
+		"id":      subtask.ID,
+		"status":  "success",
+	})
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxTestCaseSize 单个测试用例输入或输出的最大字节数
+	maxTestCaseSize = 8 << 20
+	// maxTestCases 单个题目的最大测试用例数
+	maxTestCases = 500
+)
+
+// testCaseRequest 创建或更新测试用例的请求，未提供的字段在更新时保持不变
+type testCaseRequest struct {
+	Input     *string `json:"input"`
+	Output    *string `json:"output"`
+	IsExample *bool   `json:"is_example"`
+	IsHidden  *bool   `json:"is_hidden"`
+	Weight    *int    `json:"weight"`
+	SubtaskID *uint   `json:"subtask_id"`
+}
+
+// apply 将请求中的字段写入测试用例并校验
+func (r *testCaseRequest) apply(tc *models.TestCase) error {
+	if r.Input != nil {
+		tc.Input = *r.Input
+		tc.InputFile = ""
+	}
+	if r.Output != nil {
+		tc.Output = *r.Output
+		tc.OutputFile = ""
+	}
+	if r.IsExample != nil {
+		tc.IsExample = *r.IsExample
+	}
+	if r.IsHidden != nil {
+		tc.IsHidden = *r.IsHidden
+	}
+	if r.Weight != nil {
+		tc.Weight = *r.Weight
+	}
+	if r.SubtaskID != nil {
+		tc.SubtaskID = *r.SubtaskID
+	}
+
+	if len(tc.Input) > maxTestCaseSize || len(tc.Output) > maxTestCaseSize {
+		return fmt.Errorf("test case input and output must not exceed %d bytes", maxTestCaseSize)
+	}
+	if tc.IsExample && tc.IsHidden {
+		return errors.New("an example test case cannot be hidden")
+	}
+	if tc.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	return nil
+}
+
+// findProblem 查找路由参数指定的题目，不存在时写入404响应
+func findProblem(c *gin.Context, db *gorm.DB) (*models.Problem, bool) {
+	var problem models.Problem
+	if err := db.First(&problem, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Problem not found",
+		})
+		return nil, false
+	}
+	return &problem, true
+}
+
+// findTestCase 查找属于题目的测试用例，不存在时写入404响应
+func findTestCase(c *gin.Context, db *gorm.DB, problemID uint) (*models.TestCase, bool) {
+	var tc models.TestCase
+	if err := db.Where("problem_id = ?", problemID).First(&tc, c.Param("case_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Test case not found",
+		})
+		return nil, false
+	}
+	return &tc, true
+}
+
+// GetTestCases 获取题目的全部测试用例（管理员）
+func GetTestCases(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var testCases []models.TestCase
+	if err := db.Where("problem_id = ?", problem.ID).Order("sort_order, id").Find(&testCases).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch test cases",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"test_cases": testCases,
+	})
+}
+
+// CreateTestCase 为题目添加测试用例，排在现有用例之后（管理员）
+func CreateTestCase(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var req testCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Output == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+
+	tc := models.TestCase{ProblemID: problem.ID, Weight: 1}
+	err := req.apply(&tc)
+	if err == nil {
+		err = checkSubtask(db, problem.ID, tc.SubtaskID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.TestCase{}).Where("problem_id = ?", problem.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count test cases",
+		})
+		return
+	}
+	if count >= maxTestCases {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A problem can have at most %d test cases", maxTestCases),
+		})
+		return
+	}
+
+	if err := db.Model(&models.TestCase{}).Where("problem_id = ?", problem.ID).
+		Select("COALESCE(MAX(sort_order), 0) + 1").Scan(&tc.SortOrder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create test case",
+		})
+		return
+	}
+
+	if err := db.Create(&tc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create test case: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Test case created successfully",
+		"test_case": tc,
+		"status":    "success",
+	})
+}
+
+// UpdateTestCase 更新测试用例（管理员）
+func UpdateTestCase(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+	tc, ok := findTestCase(c, db, problem.ID)
+	if !ok {
+		return
+	}
+
+	var req testCaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+	err := req.apply(tc)
+	if err == nil {
+		err = checkSubtask(db, problem.ID, tc.SubtaskID)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := db.Save(tc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update test case: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Test case updated successfully",
+		"test_case": tc,
+		"status":    "success",
+	})
+}
+
+// DeleteTestCase 删除测试用例（管理员）
+func DeleteTestCase(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+	tc, ok := findTestCase(c, db, problem.ID)
+	if !ok {
+		return
+	}
+
+	if err := db.Delete(tc).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete test case: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test case deleted successfully",
+		"id":      tc.ID,
+		"status":  "success",
+	})
+}
+
+// ReorderTestCases 按给定的ID顺序重排题目的全部测试用例（管理员）
+func ReorderTestCases(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request data",
+		})
+		return
+	}
+
+	var ids []uint
+	if err := db.Model(&models.TestCase{}).Where("problem_id = ?", problem.ID).Pluck("id", &ids).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch test cases",
+		})
+		return
+	}
+
+	// 必须恰好包含题目的全部测试用例
+	existing := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		existing[id] = true
+	}
+	if len(req.IDs) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "ids must list every test case of the problem exactly once",
+		})
+		return
+	}
+	for _, id := range req.IDs {
+		if !existing[id] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "ids must list every test case of the problem exactly once",
+			})
+			return
+		}
+		delete(existing, id)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range req.IDs {
+			if err := tx.Model(&models.TestCase{}).Where("id = ?", id).Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reorder test cases: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test cases reordered successfully",
+		"ids":     req.IDs,
+		"status":  "success",
+	})
+}
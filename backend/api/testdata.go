@@ -0,0 +1,438 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/config"
+	"backend/models"
+	"backend/storage"
+	"github.com/gin-gonic/gin"
+	"go.yaml.in/yaml/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// inlineTestDataSize 不超过该大小的输入输出直接存入 test_cases 表，更大的放入文件存储
+const inlineTestDataSize = 64 << 10
+
+// testDataStore 测试数据文件存储
+var testDataStore storage.FileStore
+
+// InitStorage 初始化测试数据文件存储
+func InitStorage(cfg *config.Config) error {
+	store, err := storage.New(cfg)
+	if err != nil {
+		return err
+	}
+	testDataStore = store
+	return nil
+}
+
+// loadTestCaseData 从文件存储读取存放在表外的输入输出，未初始化文件存储时保持原样
+func loadTestCaseData(tc *models.TestCase) error {
+	if testDataStore == nil {
+		return nil
+	}
+	if tc.InputFile != "" {
+		data, err := storage.ReadAll(testDataStore, tc.InputFile)
+		if err != nil {
+			return fmt.Errorf("load input %s: %w", tc.InputFile, err)
+		}
+		tc.Input = string(data)
+	}
+	if tc.OutputFile != "" {
+		data, err := storage.ReadAll(testDataStore, tc.OutputFile)
+		if err != nil {
+			return fmt.Errorf("load output %s: %w", tc.OutputFile, err)
+		}
+		tc.Output = string(data)
+	}
+	return nil
+}
+
+// testDataConfig 压缩包中可选的 config.yaml
+type testDataConfig struct {
+	Cases []struct {
+		Name    string `yaml:"name"`
+		Weight  *int   `yaml:"weight"`
+		Example bool   `yaml:"example"`
+		Hidden  bool   `yaml:"hidden"`
+		Subtask string `yaml:"subtask"` // 所属子任务名称，子任务需先在题目中创建
+	} `yaml:"cases"`
+}
+
+// testDataPair 压缩包中配对的输入输出文件
+type testDataPair struct {
+	name   string
+	input  *zip.File
+	output *zip.File
+}
+
+// errDataTooLarge 解压后的测试数据超过上限
+var errDataTooLarge = errors.New("test data exceeds the size limit")
+
+// budgetReader 限制累计读取字节数的 Reader，防止压缩炸弹
+type budgetReader struct {
+	r         io.Reader
+	remaining *int64
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	*b.remaining -= int64(n)
+	if *b.remaining < 0 {
+		return n, errDataTooLarge
+	}
+	return n, err
+}
+
+// parseTestDataArchive 按文件名配对 N.in/N.out（输出也可以是 N.ans），返回按名称自然排序的用例与可选配置
+func parseTestDataArchive(zr *zip.Reader) ([]testDataPair, *zip.File, error) {
+	pairs := make(map[string]*testDataPair)
+	var configFile *zip.File
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		base := path.Base(f.Name)
+		if strings.HasPrefix(base, ".") {
+			continue
+		}
+		if base == "config.yaml" || base == "config.yml" {
+			if configFile != nil {
+				return nil, nil, errors.New("archive contains more than one config.yaml")
+			}
+			configFile = f
+			continue
+		}
+
+		ext := path.Ext(base)
+		name := strings.TrimSuffix(base, ext)
+		if name == "" || (ext != ".in" && ext != ".out" && ext != ".ans") {
+			return nil, nil, fmt.Errorf("unexpected file %s, expected N.in and N.out", f.Name)
+		}
+
+		pair := pairs[name]
+		if pair == nil {
+			pair = &testDataPair{name: name}
+			pairs[name] = pair
+		}
+		target := &pair.output
+		if ext == ".in" {
+			target = &pair.input
+		}
+		if *target != nil {
+			return nil, nil, fmt.Errorf("duplicate file for test case %s", name)
+		}
+		*target = f
+	}
+
+	if len(pairs) == 0 {
+		return nil, nil, errors.New("archive contains no test cases")
+	}
+
+	result := make([]testDataPair, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.input == nil || pair.output == nil {
+			return nil, nil, fmt.Errorf("test case %s must have both an input and an output file", pair.name)
+		}
+		result = append(result, *pair)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return naturalLess(result[i].name, result[j].name)
+	})
+	return result, configFile, nil
+}
+
+// naturalLess 数字名称按数值排序，其余按字典序，数字排在前面
+func naturalLess(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return x < y
+	case errA == nil:
+		return true
+	case errB == nil:
+		return false
+	default:
+		return a < b
+	}
+}
+
+// loadTestDataConfig 读取 config.yaml，并将配置应用到按名称索引的用例，subtasks 为题目子任务名称到ID的映射
+func loadTestDataConfig(f *zip.File, cases map[string]*models.TestCase, subtasks map[string]uint) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		return err
+	}
+	var cfg testDataConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("invalid config.yaml: %w", err)
+	}
+
+	for _, item := range cfg.Cases {
+		tc, ok := cases[item.Name]
+		if !ok {
+			return fmt.Errorf("config.yaml refers to unknown test case %s", item.Name)
+		}
+		if item.Weight != nil {
+			if *item.Weight < 0 {
+				return fmt.Errorf("test case %s: weight must not be negative", item.Name)
+			}
+			tc.Weight = *item.Weight
+		}
+		if item.Example && item.Hidden {
+			return fmt.Errorf("test case %s: an example test case cannot be hidden", item.Name)
+		}
+		tc.IsExample = item.Example
+		tc.IsHidden = item.Hidden
+		if item.Subtask != "" {
+			id, ok := subtasks[item.Subtask]
+			if !ok {
+				return fmt.Errorf("test case %s: unknown subtask %s", item.Name, item.Subtask)
+			}
+			tc.SubtaskID = id
+		}
+	}
+	return nil
+}
+
+// storeTestData 保存单个输入或输出文件，小文件返回内容，大文件写入存储并返回 key
+func storeTestData(f *zip.File, key string, budget *int64) (content string, file string, err error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+	reader := &budgetReader{r: r, remaining: budget}
+
+	if f.UncompressedSize64 <= inlineTestDataSize {
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.LimitReader(reader, inlineTestDataSize+1)); err != nil {
+			return "", "", err
+		}
+		if buf.Len() > inlineTestDataSize {
+			return "", "", fmt.Errorf("%s: size does not match the archive header", f.Name)
+		}
+		return buf.String(), "", nil
+	}
+
+	if _, err := testDataStore.Put(key, reader); err != nil {
+		return "", "", err
+	}
+	return "", key, nil
+}
+
+// UploadTestData 上传测试数据压缩包，原子地替换题目的全部测试用例（管理员）
+func UploadTestData(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	cfg := config.GetConfig()
+
+	if testDataStore == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Test data storage is not available",
+		})
+		return
+	}
+
+	userID, err := getCurrentUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	maxUpload := int64(cfg.Storage.MaxUploadSize) << 20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUpload+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "A zip archive is required in the file field",
+		})
+		return
+	}
+	if header.Size > maxUpload {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Archive must not exceed %d MB", cfg.Storage.MaxUploadSize),
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read upload",
+		})
+		return
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read upload",
+		})
+		return
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	zr, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid zip archive",
+		})
+		return
+	}
+	pairs, configFile, err := parseTestDataArchive(zr)
+	if err == nil && len(pairs) > maxTestCases {
+		err = fmt.Errorf("a problem can have at most %d test cases", maxTestCases)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 先按压缩包内容构造测试用例，配置写入后再保存数据
+	testCases := make([]models.TestCase, len(pairs))
+	byName := make(map[string]*models.TestCase, len(pairs))
+	for i, pair := range pairs {
+		testCases[i] = models.TestCase{ProblemID: problem.ID, Weight: 1, SortOrder: i + 1}
+		byName[pair.name] = &testCases[i]
+	}
+	if configFile != nil {
+		var subtasks []models.Subtask
+		if err := db.Where("problem_id = ?", problem.ID).Find(&subtasks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to fetch subtasks",
+			})
+			return
+		}
+		subtaskIDs := make(map[string]uint, len(subtasks))
+		for _, s := range subtasks {
+			subtaskIDs[s.Name] = s.ID
+		}
+		if err := loadTestDataConfig(configFile, byName, subtaskIDs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	var version models.TestDataVersion
+	var prefix string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 锁定题目，同一题目的上传串行执行
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Problem{}, problem.ID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&models.TestDataVersion{}).Where("problem_id = ?", problem.ID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		version = models.TestDataVersion{
+			ProblemID:  problem.ID,
+			Version:    latest + 1,
+			CaseCount:  len(testCases),
+			Checksum:   checksum,
+			UploadedBy: userID,
+		}
+		prefix = fmt.Sprintf("problems/%d/v%d", problem.ID, version.Version)
+
+		// 保存输入输出，同时统计解压后的大小
+		budget := int64(cfg.Storage.MaxDataSize) << 20
+		for i, pair := range pairs {
+			tc := &testCases[i]
+			tc.Version = version.Version
+			var err error
+			if tc.Input, tc.InputFile, err = storeTestData(pair.input, prefix+"/"+pair.name+".in", &budget); err != nil {
+				return err
+			}
+			if tc.Output, tc.OutputFile, err = storeTestData(pair.output, prefix+"/"+pair.name+".out", &budget); err != nil {
+				return err
+			}
+		}
+		version.TotalSize = int64(cfg.Storage.MaxDataSize)<<20 - budget
+
+		// 旧用例软删除，历史提交的测试结果仍可关联
+		if err := tx.Where("problem_id = ?", problem.ID).Delete(&models.TestCase{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&testCases).Error; err != nil {
+			return err
+		}
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		if prefix != "" {
+			if err := testDataStore.Delete(prefix); err != nil {
+				log.Printf("Error cleaning up test data %s: %v", prefix, err)
+			}
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, errDataTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{
+			"error": "Failed to save test data: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Test data uploaded successfully",
+		"version": version,
+		"status":  "success",
+	})
+}
+
+// GetTestDataVersions 获取题目的测试数据版本历史（管理员）
+func GetTestDataVersions(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	problem, ok := findProblem(c, db)
+	if !ok {
+		return
+	}
+
+	var versions []models.TestDataVersion
+	if err := db.Where("problem_id = ?", problem.ID).Order("version DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch test data versions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"versions": versions,
+	})
+}
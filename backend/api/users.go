@@ -242,6 +242,18 @@ func GetUserSubmitState(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户提交状态不存在"})
 		return
 	}
+	list := make([]*models.Submission, len(submission))
+	for i := range submission {
+		list[i] = &submission[i]
+	}
+	if _, err := maskFrozenSubmissions(c, db, list...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户提交状态失败"})
+		return
+	}
+	if err := hideContestCode(c, db, list...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户提交状态失败"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"submit_state": submission,
 	})
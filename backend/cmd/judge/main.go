@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"backend/common/database"
+	"backend/config"
+	"backend/judge"
+	"backend/queue"
+	"backend/sandbox"
+)
+
+func main() {
+	// 沙箱初始化进程在此接管，不会返回
+	sandbox.Init()
+
+	configPath := flag.String("config", "./config.json", "配置文件路径")
+	flag.Parse()
+
+	// 加载配置
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	// 初始化数据库，判题机只读取题目与测试用例
+	db, err := database.InitPostgres(cfg)
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	// 初始化消息队列，进程内队列只能由API服务内嵌的判题机使用
+	if cfg.Kafka.Driver == queue.DriverMemory {
+		log.Fatalf("独立运行的判题机不支持 memory 队列，请在API服务中设置 judge.embedded")
+	}
+	q, err := queue.New(cfg)
+	if err != nil {
+		log.Fatalf("初始化消息队列失败: %v", err)
+	}
+	defer q.Close()
+
+	// 初始化判题机
+	worker, err := judge.NewWorker(db, cfg, q)
+	if err != nil {
+		log.Fatalf("初始化判题机失败: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("判题机启动，消费主题 %s（%s）", cfg.Kafka.Topic, q.Driver())
+	if err := worker.Run(ctx); err != nil {
+		log.Fatalf("判题机运行失败: %v", err)
+	}
+	log.Println("判题机已退出")
+}
@@ -0,0 +1,222 @@
+package diagnostic
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxDiagnostics 单次解析最多返回的诊断条数
+const MaxDiagnostics = 50
+
+// 编译器输出格式，对应语言配置中的 diagnostics
+const (
+	FormatGCC    = "gcc"
+	FormatGo     = "go"
+	FormatJavac  = "javac"
+	FormatPython = "python"
+)
+
+// Diagnostic 编译器或解释器输出中的一条诊断信息，行号与列号从 1 开始，0 表示未知
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // error, warning, note
+	Message  string `json:"message"`
+}
+
+// String 按 file:line:column: severity: message 的形式输出
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&b, ":%d", d.Line)
+			if d.Column > 0 {
+				fmt.Fprintf(&b, ":%d", d.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Severity)
+	b.WriteString(": ")
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+var (
+	// main.cpp:5:3: error: 'x' was not declared in this scope
+	gccPattern = regexp.MustCompile(`^([^\s:][^:]*):(\d+):(?:(\d+):)?\s*(fatal error|error|warning|note):\s*(.*)$`)
+	// ./main.go:5:2: undefined: x
+	goPattern = regexp.MustCompile(`^(\S+\.go):(\d+)(?::(\d+))?:\s*(.*)$`)
+	// Main.java:3: error: cannot find symbol
+	javacPattern = regexp.MustCompile(`^(\S+\.java):(\d+):\s*(error|warning):\s*(.*)$`)
+	// File "main.py", line 3, in <module>
+	pythonFramePattern = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)`)
+	// NameError: name 'x' is not defined
+	pythonErrorPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt|Warning))(?::\s*(.*))?$`)
+)
+
+// Parse 按编译器输出格式解析诊断信息，格式未知时按 gcc 格式解析
+func Parse(format, output string) []Diagnostic {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	var diagnostics []Diagnostic
+	switch format {
+	case FormatGo:
+		diagnostics = parseGo(lines)
+	case FormatJavac:
+		diagnostics = parseJavac(lines)
+	case FormatPython:
+		diagnostics = parsePython(lines)
+	default:
+		diagnostics = parseGCC(lines)
+	}
+	if len(diagnostics) > MaxDiagnostics {
+		diagnostics = diagnostics[:MaxDiagnostics]
+	}
+	return diagnostics
+}
+
+// FirstError 返回第一条错误，没有时返回 nil
+func FirstError(diagnostics []Diagnostic) *Diagnostic {
+	for i := range diagnostics {
+		if diagnostics[i].Severity == "error" {
+			return &diagnostics[i]
+		}
+	}
+	return nil
+}
+
+// parseGCC 解析 gcc 与 clang 的输出
+func parseGCC(lines []string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range lines {
+		m := gccPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		severity := m[4]
+		if severity == "fatal error" {
+			severity = "error"
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     m[1],
+			Line:     atoi(m[2]),
+			Column:   atoi(m[3]),
+			Severity: severity,
+			Message:  m[5],
+		})
+	}
+	return diagnostics
+}
+
+// parseGo 解析 go build 的输出，go 只报告错误
+func parseGo(lines []string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range lines {
+		m := goPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     strings.TrimPrefix(m[1], "./"),
+			Line:     atoi(m[2]),
+			Column:   atoi(m[3]),
+			Severity: "error",
+			Message:  m[4],
+		})
+	}
+	return diagnostics
+}
+
+// parseJavac 解析 javac 的输出，列号取自错误下方源码行后 ^ 所在的位置
+func parseJavac(lines []string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for i, line := range lines {
+		m := javacPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{
+			File:     m[1],
+			Line:     atoi(m[2]),
+			Severity: m[3],
+			Message:  m[4],
+		}
+		if i+2 < len(lines) {
+			d.Column = caretColumn(lines[i+2])
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// parsePython 解析 Python 的 traceback，位置取最内层的调用帧，SyntaxError 的列号取自 ^ 所在的位置
+func parsePython(lines []string) []Diagnostic {
+	var diagnostics []Diagnostic
+	var frame *Diagnostic
+	column := 0
+	for _, line := range lines {
+		if m := pythonFramePattern.FindStringSubmatch(line); m != nil {
+			frame = &Diagnostic{File: m[1], Line: atoi(m[2])}
+			column = 0
+			continue
+		}
+		if frame != nil && strings.HasPrefix(line, " ") {
+			if c := caretColumn(line); c > 0 {
+				column = c
+			}
+			continue
+		}
+		m := pythonErrorPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{Severity: "error", Message: strings.TrimSpace(m[1] + ": " + m[2])}
+		if m[2] == "" {
+			d.Message = m[1]
+		}
+		if frame != nil {
+			d.File, d.Line, d.Column = frame.File, frame.Line, column
+		}
+		diagnostics = append(diagnostics, d)
+		frame = nil
+		column = 0
+	}
+	return diagnostics
+}
+
+// caretColumn 返回只包含空白与 ^ 的行中第一个 ^ 的列号，不是这样的行时返回 0
+func caretColumn(line string) int {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.Trim(trimmed, "^~") != "" {
+		return 0
+	}
+	return strings.IndexByte(line, '^') + 1
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// signalDescriptions 常见信号的说明
+var signalDescriptions = map[string]string{
+	"SIGSEGV": "segmentation fault (invalid memory access, e.g. out-of-bounds array index or stack overflow)",
+	"SIGFPE":  "floating point exception (e.g. integer division by zero)",
+	"SIGABRT": "aborted (e.g. failed assertion or uncaught C++ exception)",
+	"SIGBUS":  "bus error (misaligned or invalid memory access)",
+	"SIGILL":  "illegal instruction",
+	"SIGKILL": "killed (usually by the time or memory limit)",
+	"SIGXCPU": "CPU time limit exceeded",
+	"SIGXFSZ": "output size limit exceeded",
+	"SIGPIPE": "broken pipe (wrote to a closed pipe)",
+	"SIGSYS":  "bad system call (forbidden by the sandbox)",
+}
+
+// SignalDescription 返回信号的说明，未知信号返回空字符串
+func SignalDescription(signal string) string {
+	return signalDescriptions[signal]
+}
@@ -0,0 +1,253 @@
+package diagnostic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name:   "gcc",
+			format: FormatGCC,
+			output: "main.cpp: In function 'int main()':\n" +
+				"main.cpp:5:5: error: 'x' was not declared in this scope\n" +
+				"    5 |     x = 1;\n" +
+				"      |     ^\n" +
+				"main.cpp:4:9: warning: unused variable 'y' [-Wunused-variable]\n" +
+				"    4 |     int y;\n" +
+				"      |         ^\n",
+			want: []Diagnostic{
+				{File: "main.cpp", Line: 5, Column: 5, Severity: "error", Message: "'x' was not declared in this scope"},
+				{File: "main.cpp", Line: 4, Column: 9, Severity: "warning", Message: "unused variable 'y' [-Wunused-variable]"},
+			},
+		},
+		{
+			name:   "gcc fatal error",
+			format: FormatGCC,
+			output: "main.cpp:1:10: fatal error: bits/stdc++.h: No such file or directory\n" +
+				"    1 | #include <bits/stdc++.h>\n" +
+				"      |          ^~~~~~~~~~~~~~~\n" +
+				"compilation terminated.\n",
+			want: []Diagnostic{
+				{File: "main.cpp", Line: 1, Column: 10, Severity: "error", Message: "bits/stdc++.h: No such file or directory"},
+			},
+		},
+		{
+			name:   "gcc linker error has no location",
+			format: FormatGCC,
+			output: "/usr/bin/ld: /tmp/ccX.o: in function `main':\n" +
+				"main.cpp:(.text+0x5): undefined reference to `foo()'\n" +
+				"collect2: error: ld returned 1 exit status\n",
+			want: nil,
+		},
+		{
+			name:   "clang",
+			format: FormatGCC,
+			output: "main.cpp:3:5: error: use of undeclared identifier 'x'\n" +
+				"    x = 1;\n" +
+				"    ^\n" +
+				"main.cpp:6:1: note: candidate function not viable\n" +
+				"1 error generated.\n",
+			want: []Diagnostic{
+				{File: "main.cpp", Line: 3, Column: 5, Severity: "error", Message: "use of undeclared identifier 'x'"},
+				{File: "main.cpp", Line: 6, Column: 1, Severity: "note", Message: "candidate function not viable"},
+			},
+		},
+		{
+			name:   "unknown format falls back to gcc",
+			format: "",
+			output: "main.c:2:1: error: expected ';' before '}' token\r\n",
+			want: []Diagnostic{
+				{File: "main.c", Line: 2, Column: 1, Severity: "error", Message: "expected ';' before '}' token"},
+			},
+		},
+		{
+			name:   "go",
+			format: FormatGo,
+			output: "# command-line-arguments\n" +
+				"./main.go:5:2: undefined: x\n" +
+				"./main.go:4:2: declared and not used: y\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 5, Column: 2, Severity: "error", Message: "undefined: x"},
+				{File: "main.go", Line: 4, Column: 2, Severity: "error", Message: "declared and not used: y"},
+			},
+		},
+		{
+			name:   "javac",
+			format: FormatJavac,
+			output: "Main.java:3: error: cannot find symbol\n" +
+				"        System.out.println(x);\n" +
+				"                           ^\n" +
+				"  symbol:   variable x\n" +
+				"  location: class Main\n" +
+				"Main.java:5: warning: [removal] Integer(int) in Integer has been deprecated\n" +
+				"        Integer i = new Integer(1);\n" +
+				"                    ^\n" +
+				"1 error\n" +
+				"1 warning\n",
+			want: []Diagnostic{
+				{File: "Main.java", Line: 3, Column: 28, Severity: "error", Message: "cannot find symbol"},
+				{File: "Main.java", Line: 5, Column: 21, Severity: "warning", Message: "[removal] Integer(int) in Integer has been deprecated"},
+			},
+		},
+		{
+			name:   "python runtime error uses the innermost frame",
+			format: FormatPython,
+			output: "Traceback (most recent call last):\n" +
+				"  File \"/sandbox/main.py\", line 4, in <module>\n" +
+				"    main()\n" +
+				"  File \"/sandbox/main.py\", line 2, in main\n" +
+				"    print(1 // 0)\n" +
+				"          ~~^~~\n" +
+				"ZeroDivisionError: integer division or modulo by zero\n",
+			want: []Diagnostic{
+				{File: "/sandbox/main.py", Line: 2, Column: 13, Severity: "error", Message: "ZeroDivisionError: integer division or modulo by zero"},
+			},
+		},
+		{
+			name:   "python syntax error",
+			format: FormatPython,
+			output: "  File \"/sandbox/main.py\", line 1\n" +
+				"    print(1\n" +
+				"         ^\n" +
+				"SyntaxError: '(' was never closed\n",
+			want: []Diagnostic{
+				{File: "/sandbox/main.py", Line: 1, Column: 10, Severity: "error", Message: "SyntaxError: '(' was never closed"},
+			},
+		},
+		{
+			name:   "python error without message",
+			format: FormatPython,
+			output: "Traceback (most recent call last):\n" +
+				"  File \"main.py\", line 1, in <module>\n" +
+				"    raise KeyboardInterrupt\n" +
+				"KeyboardInterrupt\n",
+			want: []Diagnostic{
+				{File: "main.py", Line: 1, Severity: "error", Message: "KeyboardInterrupt"},
+			},
+		},
+		{
+			name:   "truncated javac output",
+			format: FormatJavac,
+			output: "Main.java:3: error: ';' expected\n        int x = 1",
+			want: []Diagnostic{
+				{File: "Main.java", Line: 3, Severity: "error", Message: "';' expected"},
+			},
+		},
+		{
+			name:   "truncated python traceback",
+			format: FormatPython,
+			output: "Traceback (most recent call last):\n  File \"main.py\", line 3, in <mo",
+			want:   nil,
+		},
+		{
+			name:   "garbage",
+			format: FormatGCC,
+			output: "\x00\xff:::\nmain.cpp:abc: error: x\n:1:2: error: no file\nmain.cpp:5",
+			want:   nil,
+		},
+		{
+			name:   "empty",
+			format: FormatGo,
+			output: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.format, tt.output)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("diagnostic %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	var b strings.Builder
+	for i := 1; i <= MaxDiagnostics+10; i++ {
+		fmt.Fprintf(&b, "main.cpp:%d:1: error: expected ';'\n", i)
+	}
+	got := Parse(FormatGCC, b.String())
+	if len(got) != MaxDiagnostics {
+		t.Fatalf("got %d diagnostics, want %d", len(got), MaxDiagnostics)
+	}
+	if got[MaxDiagnostics-1].Line != MaxDiagnostics {
+		t.Errorf("last line = %d, want %d", got[MaxDiagnostics-1].Line, MaxDiagnostics)
+	}
+}
+
+func TestFirstError(t *testing.T) {
+	diagnostics := []Diagnostic{
+		{Severity: "warning", Message: "unused"},
+		{Severity: "error", Message: "first"},
+		{Severity: "error", Message: "second"},
+	}
+	if got := FirstError(diagnostics); got == nil || got.Message != "first" {
+		t.Errorf("FirstError = %+v, want first", got)
+	}
+	if got := FirstError(diagnostics[:1]); got != nil {
+		t.Errorf("FirstError = %+v, want nil", got)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		d    Diagnostic
+		want string
+	}{
+		{Diagnostic{File: "main.cpp", Line: 5, Column: 3, Severity: "error", Message: "x"}, "main.cpp:5:3: error: x"},
+		{Diagnostic{File: "Main.java", Line: 3, Severity: "error", Message: "x"}, "Main.java:3: error: x"},
+		{Diagnostic{File: "main.py", Severity: "error", Message: "x"}, "main.py: error: x"},
+		{Diagnostic{Severity: "error", Message: "MemoryError"}, "error: MemoryError"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSignalDescription(t *testing.T) {
+	tests := []struct {
+		signal string
+		want   string // 说明中应包含的内容，为空时说明必须为空
+	}{
+		{signal: "SIGSEGV", want: "segmentation fault"},
+		{signal: "SIGFPE", want: "division by zero"},
+		{signal: "SIGABRT", want: "assertion"},
+		{signal: "SIGKILL", want: "time or memory limit"},
+		{signal: "SIGXCPU", want: "CPU time limit"},
+		{signal: "SIGSYS", want: "sandbox"},
+		{signal: "SIGUSR1"},
+		{signal: "segv"},
+		{signal: ""},
+	}
+
+	for _, tt := range tests {
+		got := SignalDescription(tt.signal)
+		if tt.want == "" {
+			if got != "" {
+				t.Errorf("SignalDescription(%q) = %q, want empty", tt.signal, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("SignalDescription(%q) = %q, want it to mention %q", tt.signal, got, tt.want)
+		}
+	}
+}
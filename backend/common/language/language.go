@@ -0,0 +1,94 @@
+package language
+
+import (
+	"log"
+	"math"
+	"sync"
+
+	"backend/config"
+)
+
+// Language 判题语言定义
+type Language = config.LanguageConfig
+
+var (
+	registry []Language
+	byID     map[string]Language
+	once     sync.Once
+)
+
+// load 从配置加载语言注册表，跳过缺少源文件名或运行命令的语言，allowed_langs 非空时只保留其中的语言
+func load() {
+	cfg := config.GetConfig().Judge
+
+	allowed := make(map[string]bool)
+	for _, id := range cfg.AllowedLangs {
+		allowed[id] = true
+	}
+
+	byID = make(map[string]Language)
+	for _, lang := range cfg.Languages {
+		if lang.ID == "" || lang.Source == "" || len(lang.Run) == 0 {
+			log.Printf("Skipping invalid language config %q: id, source and run are required", lang.ID)
+			continue
+		}
+		if len(allowed) > 0 && !allowed[lang.ID] {
+			continue
+		}
+		if _, ok := byID[lang.ID]; ok {
+			log.Printf("Skipping duplicate language config %q", lang.ID)
+			continue
+		}
+		if lang.Name == "" {
+			lang.Name = lang.ID
+		}
+		if lang.TimeMultiplier <= 0 {
+			lang.TimeMultiplier = 1
+		}
+		if lang.MemoryMultiplier <= 0 {
+			lang.MemoryMultiplier = 1
+		}
+		byID[lang.ID] = lang
+		registry = append(registry, lang)
+	}
+}
+
+// Get 根据语言ID获取语言定义
+func Get(id string) (Language, bool) {
+	once.Do(load)
+	lang, ok := byID[id]
+	return lang, ok
+}
+
+// List 返回启用的语言，按配置中的顺序排列
+func List() []Language {
+	once.Do(load)
+	return append([]Language(nil), registry...)
+}
+
+// IDs 返回启用的语言ID，按配置中的顺序排列
+func IDs() []string {
+	once.Do(load)
+	ids := make([]string, len(registry))
+	for i, lang := range registry {
+		ids[i] = lang.ID
+	}
+	return ids
+}
+
+// helperLanguages 检查器、交互器等辅助程序支持的语言
+var helperLanguages = map[string]bool{"cpp": true, "go": true}
+
+// IsHelper 语言是否可用于检查器、交互器等辅助程序，语言还需在注册表中启用
+func IsHelper(id string) bool {
+	_, ok := Get(id)
+	return ok && helperLanguages[id]
+}
+
+// Scale 按倍数放大限制，向上取整
+func Scale(limit int, multiplier float64) int {
+	if multiplier <= 0 {
+		return limit
+	}
+	return int(math.Ceil(float64(limit) * multiplier))
+}
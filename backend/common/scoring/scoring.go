@@ -0,0 +1,198 @@
+package scoring
+
+import (
+	"math"
+
+	"backend/models"
+)
+
+// FullScore 没有子任务的题目满分
+const FullScore = 100
+
+// Case 单个测试用例的评测结果
+type Case struct {
+	SubtaskID uint
+	Weight    int
+	Passed    bool
+}
+
+// SubtaskScore 子任务得分
+type SubtaskScore struct {
+	SubtaskID uint    `json:"subtask_id"`
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"`      // 实际得分
+	FullScore float64 `json:"full_score"` // 子任务分值
+	Passed    bool    `json:"passed"`     // 全部用例通过且依赖均已通过
+}
+
+// Score 计算提交得分。题目没有子任务时按用例权重折算为百分制；
+// 有子任务时为各子任务得分之和，不属于任何子任务的用例不计分
+func Score(subtasks []models.Subtask, cases []Case) (float64, []SubtaskScore) {
+	if len(subtasks) == 0 {
+		return weighted(cases), nil
+	}
+
+	// 统计每个子任务的用例权重
+	type tally struct {
+		total, passed int
+		failed        bool
+	}
+	tallies := make(map[uint]*tally, len(subtasks))
+	for i := range subtasks {
+		tallies[subtasks[i].ID] = &tally{}
+	}
+	for _, c := range cases {
+		t := tallies[c.SubtaskID]
+		if t == nil {
+			continue
+		}
+		t.total += c.Weight
+		if c.Passed {
+			t.passed += c.Weight
+		} else {
+			t.failed = true
+		}
+	}
+
+	// 子任务自身是否全部通过，没有用例的子任务视为未通过
+	complete := make(map[uint]bool, len(subtasks))
+	for id, t := range tallies {
+		complete[id] = !t.failed && t.total > 0
+	}
+	byID := make(map[uint]*models.Subtask, len(subtasks))
+	for i := range subtasks {
+		byID[subtasks[i].ID] = &subtasks[i]
+	}
+	satisfied := dependencyResolver(byID, complete)
+
+	var total float64
+	scores := make([]SubtaskScore, 0, len(subtasks))
+	for i := range subtasks {
+		s := &subtasks[i]
+		t := tallies[s.ID]
+		result := SubtaskScore{SubtaskID: s.ID, Name: s.Name, FullScore: s.Score}
+		if satisfied(s.ID) {
+			switch {
+			case complete[s.ID]:
+				result.Score = s.Score
+			case s.Aggregation == "sum" && t.total > 0:
+				result.Score = s.Score * float64(t.passed) / float64(t.total)
+			}
+			result.Passed = complete[s.ID]
+		}
+		result.Score = round(result.Score)
+		total += result.Score
+		scores = append(scores, result)
+	}
+	return round(total), scores
+}
+
+// weighted 按用例权重折算为百分制
+func weighted(cases []Case) float64 {
+	var total, passed int
+	for _, c := range cases {
+		total += c.Weight
+		if c.Passed {
+			passed += c.Weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return round(float64(passed) * FullScore / float64(total))
+}
+
+// round 保留两位小数
+func round(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// dependencyResolver 返回判断子任务的全部依赖（递归）是否满足的函数，
+// 忽略已不存在的子任务，存在循环依赖时视为不满足
+func dependencyResolver(byID map[uint]*models.Subtask, complete map[uint]bool) func(uint) bool {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[uint]int, len(byID))
+	memo := make(map[uint]bool, len(byID))
+
+	var satisfied func(uint) bool
+	satisfied = func(id uint) bool {
+		switch state[id] {
+		case visiting:
+			return false
+		case done:
+			return memo[id]
+		}
+		state[id] = visiting
+		ok := true
+		for _, dep := range byID[id].Dependencies() {
+			if byID[dep] == nil {
+				continue
+			}
+			if !complete[dep] || !satisfied(dep) {
+				ok = false
+				break
+			}
+		}
+		state[id], memo[id] = done, ok
+		return ok
+	}
+	return satisfied
+}
+
+// Progress 记录评测过程中出现失败的子任务，用于跳过已不可能得分的用例
+type Progress struct {
+	byID   map[uint]*models.Subtask
+	failed map[uint]bool
+}
+
+// NewProgress 创建评测进度
+func NewProgress(subtasks []models.Subtask) *Progress {
+	p := &Progress{
+		byID:   make(map[uint]*models.Subtask, len(subtasks)),
+		failed: make(map[uint]bool),
+	}
+	for i := range subtasks {
+		p.byID[subtasks[i].ID] = &subtasks[i]
+	}
+	return p
+}
+
+// Record 记录用例结果
+func (p *Progress) Record(subtaskID uint, passed bool) {
+	if !passed && p.byID[subtaskID] != nil {
+		p.failed[subtaskID] = true
+	}
+}
+
+// Skip 判断子任务中的后续用例是否可以跳过：min 子任务已有用例失败，或任一依赖的子任务已有用例失败
+func (p *Progress) Skip(subtaskID uint) bool {
+	s := p.byID[subtaskID]
+	if s == nil {
+		return false
+	}
+	if p.failed[subtaskID] && s.Aggregation != "sum" {
+		return true
+	}
+	return p.dependencyFailed(subtaskID, map[uint]bool{})
+}
+
+// dependencyFailed 递归检查依赖的子任务是否已有用例失败
+func (p *Progress) dependencyFailed(subtaskID uint, seen map[uint]bool) bool {
+	if seen[subtaskID] {
+		return false
+	}
+	seen[subtaskID] = true
+	for _, dep := range p.byID[subtaskID].Dependencies() {
+		if p.byID[dep] == nil {
+			continue
+		}
+		if p.failed[dep] || p.dependencyFailed(dep, seen) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package scoring
+
+import (
+	"testing"
+
+	"backend/models"
+	"gorm.io/gorm"
+)
+
+// subtask 构造测试用的子任务
+func subtask(id uint, score float64, aggregation, dependsOn string) models.Subtask {
+	return models.Subtask{
+		Model:       gorm.Model{ID: id},
+		Name:        "subtask",
+		Score:       score,
+		Aggregation: aggregation,
+		DependsOn:   dependsOn,
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		subtasks   []models.Subtask
+		cases      []Case
+		wantTotal  float64
+		wantScores []float64 // 按子任务顺序的得分
+		wantPassed []bool
+	}{
+		{
+			name:      "no subtasks uses weights",
+			cases:     []Case{{Weight: 1, Passed: true}, {Weight: 2, Passed: false}, {Weight: 1, Passed: true}},
+			wantTotal: 50,
+		},
+		{
+			name:      "no subtasks and no cases",
+			wantTotal: 0,
+		},
+		{
+			name:      "no subtasks rounds to two decimals",
+			cases:     []Case{{Weight: 1, Passed: true}, {Weight: 1, Passed: false}, {Weight: 1, Passed: false}},
+			wantTotal: 33.33,
+		},
+		{
+			name:     "min requires every case",
+			subtasks: []models.Subtask{subtask(1, 40, "min", ""), subtask(2, 60, "min", "")},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 2, Weight: 1, Passed: true},
+				{SubtaskID: 2, Weight: 1, Passed: false},
+			},
+			wantTotal:  40,
+			wantScores: []float64{40, 0},
+			wantPassed: []bool{true, false},
+		},
+		{
+			name:     "sum scores by passed weight",
+			subtasks: []models.Subtask{subtask(1, 40, "sum", ""), subtask(2, 60, "min", "")},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 3, Passed: true},
+				{SubtaskID: 1, Weight: 1, Passed: false},
+				{SubtaskID: 2, Weight: 1, Passed: false},
+			},
+			wantTotal:  30,
+			wantScores: []float64{30, 0},
+			wantPassed: []bool{false, false},
+		},
+		{
+			name:     "empty subtask scores nothing",
+			subtasks: []models.Subtask{subtask(1, 50, "min", ""), subtask(2, 50, "sum", "")},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+			},
+			wantTotal:  50,
+			wantScores: []float64{50, 0},
+			wantPassed: []bool{true, false},
+		},
+		{
+			name:     "cases outside subtasks are ignored",
+			subtasks: []models.Subtask{subtask(1, 100, "min", "")},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 0, Weight: 1, Passed: false},
+				{SubtaskID: 9, Weight: 1, Passed: false},
+			},
+			wantTotal:  100,
+			wantScores: []float64{100},
+			wantPassed: []bool{true},
+		},
+		{
+			name: "dependency chain all passed",
+			subtasks: []models.Subtask{
+				subtask(1, 20, "min", ""),
+				subtask(2, 30, "min", "1"),
+				subtask(3, 50, "min", "2"),
+			},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 2, Weight: 1, Passed: true},
+				{SubtaskID: 3, Weight: 1, Passed: true},
+			},
+			wantTotal:  100,
+			wantScores: []float64{20, 30, 50},
+			wantPassed: []bool{true, true, true},
+		},
+		{
+			name: "failed prerequisite zeroes the whole chain",
+			subtasks: []models.Subtask{
+				subtask(1, 20, "min", ""),
+				subtask(2, 30, "min", "1"),
+				subtask(3, 50, "sum", "2"),
+			},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: false},
+				{SubtaskID: 2, Weight: 1, Passed: true},
+				{SubtaskID: 3, Weight: 1, Passed: true},
+			},
+			wantTotal:  0,
+			wantScores: []float64{0, 0, 0},
+			wantPassed: []bool{false, false, false},
+		},
+		{
+			name: "partially passed sum prerequisite still blocks dependents",
+			subtasks: []models.Subtask{
+				subtask(1, 40, "sum", ""),
+				subtask(2, 60, "min", "1"),
+			},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 1, Weight: 1, Passed: false},
+				{SubtaskID: 2, Weight: 1, Passed: true},
+			},
+			wantTotal:  20,
+			wantScores: []float64{20, 0},
+			wantPassed: []bool{false, false},
+		},
+		{
+			name: "deleted dependency is ignored",
+			subtasks: []models.Subtask{
+				subtask(1, 100, "min", "7"),
+			},
+			cases:      []Case{{SubtaskID: 1, Weight: 1, Passed: true}},
+			wantTotal:  100,
+			wantScores: []float64{100},
+			wantPassed: []bool{true},
+		},
+		{
+			name: "dependency cycle is not satisfied",
+			subtasks: []models.Subtask{
+				subtask(1, 50, "min", "2"),
+				subtask(2, 50, "min", "1"),
+			},
+			cases: []Case{
+				{SubtaskID: 1, Weight: 1, Passed: true},
+				{SubtaskID: 2, Weight: 1, Passed: true},
+			},
+			wantTotal:  0,
+			wantScores: []float64{0, 0},
+			wantPassed: []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, scores := Score(tt.subtasks, tt.cases)
+			if total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", total, tt.wantTotal)
+			}
+			if len(tt.subtasks) == 0 {
+				if scores != nil {
+					t.Errorf("scores = %v, want nil", scores)
+				}
+				return
+			}
+			if len(scores) != len(tt.subtasks) {
+				t.Fatalf("got %d subtask scores, want %d", len(scores), len(tt.subtasks))
+			}
+			for i, s := range scores {
+				if s.SubtaskID != tt.subtasks[i].ID || s.FullScore != tt.subtasks[i].Score {
+					t.Errorf("subtask %d = %+v, want id %d full score %v", i, s, tt.subtasks[i].ID, tt.subtasks[i].Score)
+				}
+				if s.Score != tt.wantScores[i] || s.Passed != tt.wantPassed[i] {
+					t.Errorf("subtask %d = (%v, %v), want (%v, %v)", s.SubtaskID, s.Score, s.Passed, tt.wantScores[i], tt.wantPassed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	subtasks := []models.Subtask{
+		subtask(1, 20, "min", ""),
+		subtask(2, 30, "sum", ""),
+		subtask(3, 25, "min", "1"),
+		subtask(4, 25, "sum", "3"),
+	}
+
+	type record struct {
+		subtaskID uint
+		passed    bool
+	}
+	tests := []struct {
+		name    string
+		records []record
+		want    map[uint]bool // 各子任务是否跳过
+	}{
+		{
+			name: "nothing recorded",
+			want: map[uint]bool{0: false, 1: false, 2: false, 3: false, 4: false},
+		},
+		{
+			name:    "passing cases skip nothing",
+			records: []record{{1, true}, {2, true}, {3, true}},
+			want:    map[uint]bool{1: false, 2: false, 3: false, 4: false},
+		},
+		{
+			name:    "failed min subtask skips itself and its dependents",
+			records: []record{{1, false}},
+			want:    map[uint]bool{1: true, 2: false, 3: true, 4: true},
+		},
+		{
+			name:    "failed sum subtask keeps running",
+			records: []record{{2, false}},
+			want:    map[uint]bool{1: false, 2: false, 3: false, 4: false},
+		},
+		{
+			name:    "failure in the middle of a chain",
+			records: []record{{1, true}, {3, false}},
+			want:    map[uint]bool{1: false, 2: false, 3: true, 4: true},
+		},
+		{
+			name:    "cases outside subtasks are never skipped",
+			records: []record{{0, false}, {9, false}},
+			want:    map[uint]bool{0: false, 9: false, 1: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProgress(subtasks)
+			for _, r := range tt.records {
+				p.Record(r.subtaskID, r.passed)
+			}
+			for id, want := range tt.want {
+				if got := p.Skip(id); got != want {
+					t.Errorf("Skip(%d) = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProgressDependencyCycle(t *testing.T) {
+	p := NewProgress([]models.Subtask{
+		subtask(1, 50, "min", "2"),
+		subtask(2, 50, "min", "1"),
+	})
+	if p.Skip(1) || p.Skip(2) {
+		t.Fatal("cycle without failures must not skip")
+	}
+	p.Record(2, false)
+	if !p.Skip(1) || !p.Skip(2) {
+		t.Error("failure in a cycle must skip both subtasks")
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,6 +15,9 @@ type Config struct {
 	Redis    RedisConfig    `mapstructure:"redis"`
 	Kafka    KafkaConfig    `mapstructure:"kafka"`
 	Judge    JudgeConfig    `mapstructure:"judge"`
+	Sandbox  SandboxConfig  `mapstructure:"sandbox"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	Reaper   ReaperConfig   `mapstructure:"reaper"`
 }
 
 // ServerConfig 服务器配置
@@ -42,18 +46,150 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
-// KafkaConfig Kafka配置
+// KafkaConfig 消息队列配置，主题名称对所有驱动通用
 type KafkaConfig struct {
-	Brokers     []string `mapstructure:"brokers"`
-	Topic       string   `mapstructure:"topic"`
-	ResultTopic string   `mapstructure:"result_topic"`
+	Driver     string `mapstructure:"driver"`     // 队列驱动：kafka、redis（Redis Streams）或 memory（进程内，需内嵌判题机）
+	Partitions int    `mapstructure:"partitions"` // redis 与 memory 驱动每个主题的分区数
+
+	Brokers        []string `mapstructure:"brokers"`
+	Topic          string   `mapstructure:"topic"`
+	ResultTopic    string   `mapstructure:"result_topic"`
+	HeartbeatTopic string   `mapstructure:"heartbeat_topic"`
+	RejudgeTopic   string   `mapstructure:"rejudge_topic"` // 重新评测的判题任务，优先级低于普通提交
+	ContestTopic   string   `mapstructure:"contest_topic"` // 比赛中提交的判题任务，优先级高于普通提交
+	RunTopic       string   `mapstructure:"run_topic"`     // 自定义输入运行的任务，不创建提交记录
+
+	DeadLetterTopic  string `mapstructure:"dead_letter_topic"`  // 处理失败的判题结果
+	ResultMaxRetries int    `mapstructure:"result_max_retries"` // 判题结果处理失败后的重试次数
+	ResultRetryDelay int    `mapstructure:"result_retry_delay"` // 首次重试前的等待时间，毫秒，之后每次翻倍
 }
 
 // JudgeConfig 判题配置
 type JudgeConfig struct {
 	Timeout      int      `mapstructure:"timeout"`
 	MaxMemory    int      `mapstructure:"max_memory"`
-	AllowedLangs []string `mapstructure:"allowed_langs"`
+	AllowedLangs []string `mapstructure:"allowed_langs"` // 非空时只启用列出的语言
+	WorkDir      string   `mapstructure:"work_dir"`
+
+	Languages []LanguageConfig `mapstructure:"languages"` // 语言注册表，API 服务与判题机需一致
+
+	Name              string `mapstructure:"name"`               // 判题机名称，默认使用主机名
+	Concurrency       int    `mapstructure:"concurrency"`        // 单个判题机的最大并发判题数
+	HeartbeatInterval int    `mapstructure:"heartbeat_interval"` // 心跳间隔，秒
+	Embedded          bool   `mapstructure:"embedded"`           // 在API服务进程内运行判题机，memory 驱动时总是内嵌
+	MaxUserTasks      int    `mapstructure:"max_user_tasks"`     // 每个用户同时在队列中或评测中的判题任务上限，0 表示不限制，重新评测不计入
+
+	SubmitRate         int `mapstructure:"submit_rate"`          // 每个用户每分钟可提交次数，0 表示不限制
+	SubmitBurst        int `mapstructure:"submit_burst"`         // 每个用户令牌桶容量，0 表示等于 submit_rate
+	ProblemSubmitRate  int `mapstructure:"problem_submit_rate"`  // 每个用户每道题每分钟可提交次数，0 表示不限制
+	ProblemSubmitBurst int `mapstructure:"problem_submit_burst"` // 每个用户每道题令牌桶容量，0 表示等于 problem_submit_rate
+	DuplicateWindow    int `mapstructure:"duplicate_window"`     // 拒绝向同一题目重复提交相同代码的时间窗口，秒，0 表示不检查
+
+	CompileLogPolicy   string `mapstructure:"compile_log_policy"`    // 提交者可见的编译信息：full, diagnostics, first_error, none，管理员总是可见全部
+	CompileLogMaxBytes int    `mapstructure:"compile_log_max_bytes"` // 提交者可见的编译输出最大字节数，0 表示不截断
+
+	RunRate        int `mapstructure:"run_rate"`         // 每个用户每分钟可运行自定义输入的次数，0 表示不限制
+	RunBurst       int `mapstructure:"run_burst"`        // 运行自定义输入的令牌桶容量，0 表示等于 run_rate
+	RunTimeLimit   int `mapstructure:"run_time_limit"`   // 未指定题目时运行自定义输入的时间限制，毫秒
+	RunMemoryLimit int `mapstructure:"run_memory_limit"` // 未指定题目时运行自定义输入的内存限制，MB
+}
+
+// defaultHeartbeatInterval heartbeat_interval 未设置时的心跳间隔
+const defaultHeartbeatInterval = 10 * time.Second
+
+// Heartbeat 判题机的心跳间隔，未设置或不为正数时为 10 秒。判题机发送心跳与 API 服务判断判题机是否在线均使用此值
+func (c *JudgeConfig) Heartbeat() time.Duration {
+	if c.HeartbeatInterval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return time.Duration(c.HeartbeatInterval) * time.Second
+}
+
+// LanguageConfig 判题语言配置，命令在判题目录中执行
+type LanguageConfig struct {
+	ID               string   `mapstructure:"id" json:"id"`
+	Name             string   `mapstructure:"name" json:"name"`                           // 显示名称
+	Source           string   `mapstructure:"source" json:"source"`                       // 源文件名
+	Compile          []string `mapstructure:"compile" json:"compile,omitempty"`           // 编译命令，为空表示无需编译
+	Run              []string `mapstructure:"run" json:"run"`                             // 运行命令
+	Version          string   `mapstructure:"version" json:"version"`                     // 编译器或解释器版本，仅用于展示
+	TimeMultiplier   float64  `mapstructure:"time_multiplier" json:"time_multiplier"`     // 时间限制倍数，0 表示 1
+	MemoryMultiplier float64  `mapstructure:"memory_multiplier" json:"memory_multiplier"` // 内存限制倍数，0 表示 1
+	Diagnostics      string   `mapstructure:"diagnostics" json:"diagnostics"`             // 编译与运行错误输出的格式：gcc, go, javac, python，为空时按 gcc 解析
+}
+
+// defaultLanguages 默认语言注册表
+func defaultLanguages() []LanguageConfig {
+	return []LanguageConfig{
+		{
+			ID:          "cpp",
+			Name:        "C++",
+			Source:      "main.cpp",
+			Compile:     []string{"g++", "-O2", "-std=c++17", "-o", "main", "main.cpp"},
+			Run:         []string{"./main"},
+			Version:     "GCC, C++17",
+			Diagnostics: "gcc",
+		},
+		{
+			ID:          "go",
+			Name:        "Go",
+			Source:      "main.go",
+			Compile:     []string{"go", "build", "-o", "main", "main.go"},
+			Run:         []string{"./main"},
+			Version:     "Go",
+			Diagnostics: "go",
+		},
+		// JVM 按 CPU 核数创建 GC 与 JIT 线程，多核判题机上会超过沙箱的进程数限制，固定为单核与串行 GC
+		{
+			ID:          "java",
+			Name:        "Java",
+			Source:      "Main.java",
+			Compile:     []string{"javac", "-J-XX:+UseSerialGC", "-J-XX:ActiveProcessorCount=1", "-encoding", "UTF-8", "Main.java"},
+			Run:         []string{"java", "-XX:+UseSerialGC", "-XX:ActiveProcessorCount=1", "-cp", ".", "Main"},
+			Version:     "OpenJDK",
+			Diagnostics: "javac",
+			// JVM 启动与 JIT 预热较慢，堆与元空间占用较大
+			TimeMultiplier:   2,
+			MemoryMultiplier: 2,
+		},
+		{
+			ID:          "python",
+			Name:        "Python 3",
+			Source:      "main.py",
+			Run:         []string{"python3", "main.py"},
+			Version:     "CPython 3",
+			Diagnostics: "python",
+			// 解释执行通常比 C++ 慢数倍，对象开销使内存占用更大
+			TimeMultiplier:   3,
+			MemoryMultiplier: 2,
+		},
+	}
+}
+
+// SandboxConfig 判题沙箱配置
+type SandboxConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	CgroupRoot    string   `mapstructure:"cgroup_root"` // 委派给判题机的 cgroup v2 目录，为空时仅使用 rlimit
+	UID           int      `mapstructure:"uid"`         // 以 root 运行判题机时沙箱内使用的用户
+	GID           int      `mapstructure:"gid"`
+	PidsLimit     int      `mapstructure:"pids_limit"`
+	OutputLimit   int      `mapstructure:"output_limit"` // MB
+	ReadOnlyPaths []string `mapstructure:"read_only_paths"`
+}
+
+// StorageConfig 测试数据存储配置
+type StorageConfig struct {
+	Driver        string `mapstructure:"driver"`          // 存储驱动，目前支持 local
+	LocalDir      string `mapstructure:"local_dir"`       // local 驱动的根目录，API 服务与判题机需共享
+	MaxUploadSize int    `mapstructure:"max_upload_size"` // 测试数据压缩包大小上限，MB
+	MaxDataSize   int    `mapstructure:"max_data_size"`   // 解压后测试数据总大小上限，MB
+}
+
+// ReaperConfig 超时提交回收配置
+type ReaperConfig struct {
+	Interval    int `mapstructure:"interval"`     // 扫描间隔，秒
+	Deadline    int `mapstructure:"deadline"`     // 判题任务发送后允许的评测时间，秒，尚未发送的任务不计时
+	MaxRequeues int `mapstructure:"max_requeues"` // 重新评测次数上限，超过后标记为 system_error
 }
 
 var (
@@ -118,14 +254,61 @@ func setDefaults() {
 	viper.SetDefault("redis.db", 0)
 
 	// Kafka defaults
+	viper.SetDefault("kafka.driver", "kafka")
+	viper.SetDefault("kafka.partitions", 4)
 	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
 	viper.SetDefault("kafka.topic", "judge-tasks")
 	viper.SetDefault("kafka.result_topic", "judge-results")
+	viper.SetDefault("kafka.heartbeat_topic", "judge-heartbeats")
+	viper.SetDefault("kafka.rejudge_topic", "judge-tasks-rejudge")
+	viper.SetDefault("kafka.contest_topic", "judge-tasks-contest")
+	viper.SetDefault("kafka.run_topic", "judge-runs")
+	viper.SetDefault("kafka.dead_letter_topic", "judge-results-dlq")
+	viper.SetDefault("kafka.result_max_retries", 5)
+	viper.SetDefault("kafka.result_retry_delay", 500)
 
 	// Judge defaults
 	viper.SetDefault("judge.timeout", 10000)
 	viper.SetDefault("judge.max_memory", 256)
-	viper.SetDefault("judge.allowed_langs", []string{"go", "cpp", "java", "python"})
+	viper.SetDefault("judge.allowed_langs", []string{})
+	viper.SetDefault("judge.languages", defaultLanguages())
+	viper.SetDefault("judge.work_dir", "/tmp/oj-judge")
+	viper.SetDefault("judge.name", "")
+	viper.SetDefault("judge.concurrency", 1)
+	viper.SetDefault("judge.heartbeat_interval", 10)
+	viper.SetDefault("judge.embedded", false)
+	viper.SetDefault("judge.max_user_tasks", 3)
+	viper.SetDefault("judge.submit_rate", 10)
+	viper.SetDefault("judge.submit_burst", 10)
+	viper.SetDefault("judge.problem_submit_rate", 4)
+	viper.SetDefault("judge.problem_submit_burst", 6)
+	viper.SetDefault("judge.duplicate_window", 60)
+	viper.SetDefault("judge.compile_log_policy", "full")
+	viper.SetDefault("judge.compile_log_max_bytes", 8192)
+	viper.SetDefault("judge.run_rate", 20)
+	viper.SetDefault("judge.run_burst", 10)
+	viper.SetDefault("judge.run_time_limit", 2000)
+	viper.SetDefault("judge.run_memory_limit", 256)
+
+	// Sandbox defaults
+	viper.SetDefault("sandbox.enabled", true)
+	viper.SetDefault("sandbox.cgroup_root", "/sys/fs/cgroup/oj-judge")
+	viper.SetDefault("sandbox.uid", 65534)
+	viper.SetDefault("sandbox.gid", 65534)
+	viper.SetDefault("sandbox.pids_limit", 64)
+	viper.SetDefault("sandbox.output_limit", 64)
+	viper.SetDefault("sandbox.read_only_paths", []string{"/bin", "/lib", "/lib64", "/usr", "/etc"})
+
+	// Storage defaults
+	viper.SetDefault("storage.driver", "local")
+	viper.SetDefault("storage.local_dir", "./data/testdata")
+	viper.SetDefault("storage.max_upload_size", 256)
+	viper.SetDefault("storage.max_data_size", 1024)
+
+	// Reaper defaults
+	viper.SetDefault("reaper.interval", 30)
+	viper.SetDefault("reaper.deadline", 300)
+	viper.SetDefault("reaper.max_requeues", 2)
 }
 
 // 默认配置
@@ -152,14 +335,68 @@ func getDefaultConfig() *Config {
 			DB:       viper.GetInt("redis.db"),
 		},
 		Kafka: KafkaConfig{
-			Brokers:     viper.GetStringSlice("kafka.brokers"),
-			Topic:       viper.GetString("kafka.topic"),
-			ResultTopic: viper.GetString("kafka.result_topic"),
+			Driver:     viper.GetString("kafka.driver"),
+			Partitions: viper.GetInt("kafka.partitions"),
+
+			Brokers:        viper.GetStringSlice("kafka.brokers"),
+			Topic:          viper.GetString("kafka.topic"),
+			ResultTopic:    viper.GetString("kafka.result_topic"),
+			HeartbeatTopic: viper.GetString("kafka.heartbeat_topic"),
+			RejudgeTopic:   viper.GetString("kafka.rejudge_topic"),
+			ContestTopic:   viper.GetString("kafka.contest_topic"),
+			RunTopic:       viper.GetString("kafka.run_topic"),
+
+			DeadLetterTopic:  viper.GetString("kafka.dead_letter_topic"),
+			ResultMaxRetries: viper.GetInt("kafka.result_max_retries"),
+			ResultRetryDelay: viper.GetInt("kafka.result_retry_delay"),
 		},
 		Judge: JudgeConfig{
 			Timeout:      viper.GetInt("judge.timeout"),
 			MaxMemory:    viper.GetInt("judge.max_memory"),
 			AllowedLangs: viper.GetStringSlice("judge.allowed_langs"),
+			WorkDir:      viper.GetString("judge.work_dir"),
+
+			Languages: defaultLanguages(),
+
+			Name:              viper.GetString("judge.name"),
+			Concurrency:       viper.GetInt("judge.concurrency"),
+			HeartbeatInterval: viper.GetInt("judge.heartbeat_interval"),
+			Embedded:          viper.GetBool("judge.embedded"),
+			MaxUserTasks:      viper.GetInt("judge.max_user_tasks"),
+
+			SubmitRate:         viper.GetInt("judge.submit_rate"),
+			SubmitBurst:        viper.GetInt("judge.submit_burst"),
+			ProblemSubmitRate:  viper.GetInt("judge.problem_submit_rate"),
+			ProblemSubmitBurst: viper.GetInt("judge.problem_submit_burst"),
+			DuplicateWindow:    viper.GetInt("judge.duplicate_window"),
+
+			CompileLogPolicy:   viper.GetString("judge.compile_log_policy"),
+			CompileLogMaxBytes: viper.GetInt("judge.compile_log_max_bytes"),
+
+			RunRate:        viper.GetInt("judge.run_rate"),
+			RunBurst:       viper.GetInt("judge.run_burst"),
+			RunTimeLimit:   viper.GetInt("judge.run_time_limit"),
+			RunMemoryLimit: viper.GetInt("judge.run_memory_limit"),
+		},
+		Sandbox: SandboxConfig{
+			Enabled:       viper.GetBool("sandbox.enabled"),
+			CgroupRoot:    viper.GetString("sandbox.cgroup_root"),
+			UID:           viper.GetInt("sandbox.uid"),
+			GID:           viper.GetInt("sandbox.gid"),
+			PidsLimit:     viper.GetInt("sandbox.pids_limit"),
+			OutputLimit:   viper.GetInt("sandbox.output_limit"),
+			ReadOnlyPaths: viper.GetStringSlice("sandbox.read_only_paths"),
+		},
+		Storage: StorageConfig{
+			Driver:        viper.GetString("storage.driver"),
+			LocalDir:      viper.GetString("storage.local_dir"),
+			MaxUploadSize: viper.GetInt("storage.max_upload_size"),
+			MaxDataSize:   viper.GetInt("storage.max_data_size"),
+		},
+		Reaper: ReaperConfig{
+			Interval:    viper.GetInt("reaper.interval"),
+			Deadline:    viper.GetInt("reaper.deadline"),
+			MaxRequeues: viper.GetInt("reaper.max_requeues"),
 		},
 	}
 }
@@ -0,0 +1,275 @@
+package judge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/models"
+	"backend/sandbox"
+)
+
+const (
+	// checkerTimeout 检查器与交互器的 CPU 时间限制
+	checkerTimeout = 10 * time.Second
+	// checkerMemoryLimit 检查器与交互器的内存限制
+	checkerMemoryLimit = 512 << 20
+	// checkerMountPoint 检查器目录在沙箱内的挂载点
+	checkerMountPoint = "/checker"
+)
+
+// testlib 检查器退出码
+const (
+	testlibOK          = 0
+	testlibWrongAnswer = 1
+	testlibPresentErr  = 2
+	testlibFail        = 3
+	testlibPoints      = 7
+)
+
+// defaultFloatPrecision 未设置精度时浮点比较允许的误差
+const defaultFloatPrecision = 1e-6
+
+// checkOutput 按题目的检查模式比较输出，返回是否通过与说明
+func checkOutput(problem *models.Problem, expected, actual string) (bool, string) {
+	switch problem.CheckerMode {
+	case "whitespace":
+		if tokensMatch(expected, actual) {
+			return true, ""
+		}
+		return false, "output differs from the expected answer"
+	case "float":
+		return floatsMatch(expected, actual, floatPrecision(problem))
+	default:
+		return outputsMatch(expected, actual), ""
+	}
+}
+
+// lineMatcher 样例差异中按题目的内置比较方式判断一行是否相同，浮点比较时误差范围内的行视为相同
+func lineMatcher(problem *models.Problem) func(expected, actual string) bool {
+	switch problem.CheckerMode {
+	case "whitespace":
+		return tokensMatch
+	case "float":
+		precision := floatPrecision(problem)
+		return func(expected, actual string) bool {
+			passed, _ := floatsMatch(expected, actual, precision)
+			return passed
+		}
+	default:
+		return func(expected, actual string) bool {
+			return expected == actual
+		}
+	}
+}
+
+// floatPrecision 浮点比较允许的误差，未设置时使用默认值
+func floatPrecision(problem *models.Problem) float64 {
+	if problem.FloatPrecision <= 0 {
+		return defaultFloatPrecision
+	}
+	return problem.FloatPrecision
+}
+
+// tokensMatch 忽略所有空白差异，逐个比较以空白分隔的单词
+func tokensMatch(expected, actual string) bool {
+	e, a := strings.Fields(expected), strings.Fields(actual)
+	if len(e) != len(a) {
+		return false
+	}
+	for i := range e {
+		if e[i] != a[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// floatsMatch 逐个比较单词，两边都是数字时允许绝对或相对误差不超过 precision
+func floatsMatch(expected, actual string, precision float64) (bool, string) {
+	e, a := strings.Fields(expected), strings.Fields(actual)
+	if len(e) != len(a) {
+		return false, fmt.Sprintf("expected %d tokens, found %d", len(e), len(a))
+	}
+	for i := range e {
+		x, errX := strconv.ParseFloat(e[i], 64)
+		y, errY := strconv.ParseFloat(a[i], 64)
+		if errX != nil || errY != nil {
+			if e[i] != a[i] {
+				return false, fmt.Sprintf("token %d: expected %q, found %q", i+1, truncate(e[i], 64), truncate(a[i], 64))
+			}
+			continue
+		}
+		if math.IsNaN(y) || math.IsInf(y, 0) {
+			return false, fmt.Sprintf("token %d: expected %s, found %s", i+1, e[i], a[i])
+		}
+		diff := math.Abs(x - y)
+		if diff > precision && diff > precision*math.Abs(x) {
+			return false, fmt.Sprintf("token %d: expected %s, found %s, error %g exceeds %g", i+1, e[i], a[i], diff, precision)
+		}
+	}
+	return true, ""
+}
+
+// prepareProblemHelper 准备题目的交互器或自定义检查器，题目不需要时返回空目录
+func (w *Worker) prepareProblemHelper(problem *models.Problem) (string, error) {
+	switch {
+	case problem.JudgeMode == "interactive":
+		dir, err := w.prepareHelper(problem.InteractorLanguage, problem.InteractorCode)
+		if err != nil {
+			return "", fmt.Errorf("prepare interactor: %w", err)
+		}
+		return dir, nil
+	case problem.CheckerMode == "custom":
+		dir, err := w.prepareHelper(problem.CheckerLanguage, problem.CheckerCode)
+		if err != nil {
+			return "", fmt.Errorf("prepare checker: %w", err)
+		}
+		return dir, nil
+	}
+	return "", nil
+}
+
+// prepareHelper 编译检查器、交互器等辅助程序并返回其所在目录，编译结果按代码哈希缓存
+func (w *Worker) prepareHelper(language, code string) (string, error) {
+	lang, ok := GetLanguage(language)
+	if !ok || !isHelperLanguage(language) {
+		return "", fmt.Errorf("unsupported helper language: %s", language)
+	}
+
+	sum := sha256.Sum256([]byte(language + "\x00" + code))
+	dir := filepath.Join(w.cacheDir, "helpers", hex.EncodeToString(sum[:]))
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "build-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.WriteFile(filepath.Join(tmp, lang.Source), []byte(code), 0644); err != nil {
+		return "", err
+	}
+	res := w.execute(&execSpec{
+		Args:        lang.Compile,
+		Env:         w.compileEnv(),
+		Dir:         tmp,
+		Writable:    true,
+		Mounts:      w.cacheMounts(),
+		TimeLimit:   compileTimeout,
+		WallLimit:   compileTimeout,
+		MemoryLimit: compileMemoryLimit,
+		PidsLimit:   compilePidsLimit,
+	})
+	if res.Err != nil {
+		return "", res.Err
+	}
+	if res.TimedOut || res.Signal != "" || res.ExitCode != 0 {
+		return "", fmt.Errorf("compilation failed:\n%s", truncate(res.Stderr+res.Stdout, maxReportSize))
+	}
+
+	// 并发编译同一程序时，后完成的一方直接使用已有结果
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// helperArgs 辅助程序的运行命令，沙箱内位于挂载点，未启用沙箱时直接使用宿主机路径
+func (w *Worker) helperArgs(helperDir, language, mountPoint string, args ...string) []string {
+	lang, _ := GetLanguage(language)
+	binDir := helperDir
+	if w.cfg.Sandbox.Enabled {
+		binDir = mountPoint
+	}
+	run := append([]string{filepath.Join(binDir, strings.TrimPrefix(lang.Run[0], "./"))}, lang.Run[1:]...)
+	return append(run, args...)
+}
+
+// writeHelperFiles 创建辅助程序的工作目录并写入文件
+func (w *Worker) writeHelperFiles(prefix string, files map[string]string) (string, error) {
+	dir, err := os.MkdirTemp(w.cfg.Judge.WorkDir, prefix)
+	if err != nil {
+		return "", err
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// testlibVerdict 将 testlib 退出码转换为是否通过，检查器自身失败时返回错误
+func testlibVerdict(name string, res *runResult) (bool, string, error) {
+	message := strings.TrimSpace(res.Stderr)
+	if message == "" {
+		message = strings.TrimSpace(res.Stdout)
+	}
+	message = truncate(message, maxReportSize)
+
+	switch {
+	case res.TimedOut:
+		return false, "", fmt.Errorf("%s timed out", name)
+	case res.Signal != "":
+		return false, "", fmt.Errorf("%s killed by signal %s", name, res.Signal)
+	case res.ExitCode == testlibOK:
+		return true, message, nil
+	case res.ExitCode == testlibWrongAnswer, res.ExitCode == testlibPresentErr, res.ExitCode == testlibPoints:
+		return false, message, nil
+	case res.ExitCode == testlibFail:
+		return false, "", fmt.Errorf("%s failed: %s", name, message)
+	default:
+		return false, "", fmt.Errorf("%s exited with code %d: %s", name, res.ExitCode, message)
+	}
+}
+
+// judgeOutput 判断用户输出是否正确，自定义检查器出错时返回错误
+func (w *Worker) judgeOutput(problem *models.Problem, checkerDir string, tc *models.TestCase, output string) (bool, string, error) {
+	if problem.CheckerMode != "custom" {
+		passed, message := checkOutput(problem, tc.Output, output)
+		return passed, message, nil
+	}
+	return w.runChecker(checkerDir, problem.CheckerLanguage, tc.Input, tc.Output, output)
+}
+
+// runChecker 运行自定义检查器，参数顺序与 testlib 一致：输入、用户输出、标准答案
+func (w *Worker) runChecker(checkerDir, language, input, expected, actual string) (bool, string, error) {
+	dir, err := w.writeHelperFiles("checker-", map[string]string{
+		"input.txt":  input,
+		"output.txt": actual,
+		"answer.txt": expected,
+	})
+	if err != nil {
+		return false, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	res := w.execute(&execSpec{
+		Args:        w.helperArgs(checkerDir, language, checkerMountPoint, "input.txt", "output.txt", "answer.txt"),
+		Env:         runEnv,
+		Dir:         dir,
+		Mounts:      []sandbox.Mount{{Source: checkerDir, Target: checkerMountPoint}},
+		TimeLimit:   checkerTimeout,
+		MemoryLimit: checkerMemoryLimit,
+		PidsLimit:   w.cfg.Sandbox.PidsLimit,
+	})
+	if res.Err != nil {
+		return false, "", res.Err
+	}
+	return testlibVerdict("checker", res)
+}
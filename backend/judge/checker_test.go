@@ -0,0 +1,93 @@
+package judge
+
+import (
+	"strings"
+	"testing"
+
+	"backend/models"
+)
+
+func TestCheckOutput(t *testing.T) {
+	exact := &models.Problem{CheckerMode: "exact"}
+	whitespace := &models.Problem{CheckerMode: "whitespace"}
+	float := &models.Problem{CheckerMode: "float", FloatPrecision: 1e-6}
+	defaultFloat := &models.Problem{CheckerMode: "float"}
+
+	tests := []struct {
+		name        string
+		problem     *models.Problem
+		expected    string
+		actual      string
+		wantPassed  bool
+		wantMessage string // 为空时不检查
+	}{
+		{name: "exact match", problem: exact, expected: "1 2\n3\n", actual: "1 2\n3\n", wantPassed: true},
+		{name: "exact ignores trailing whitespace", problem: exact, expected: "1 2\n3\n", actual: "1 2  \n3\t\n", wantPassed: true},
+		{name: "exact ignores trailing blank lines", problem: exact, expected: "1\n", actual: "1\n\n\n", wantPassed: true},
+		{name: "exact ignores crlf", problem: exact, expected: "1\n2\n", actual: "1\r\n2\r\n", wantPassed: true},
+		{name: "exact missing trailing newline", problem: exact, expected: "1\n", actual: "1", wantPassed: true},
+		{name: "exact inner whitespace differs", problem: exact, expected: "1 2\n", actual: "1  2\n"},
+		{name: "exact leading blank line differs", problem: exact, expected: "1\n", actual: "\n1\n"},
+		{name: "unknown mode is exact", problem: &models.Problem{}, expected: "1\n", actual: "2\n"},
+
+		{name: "whitespace ignores layout", problem: whitespace, expected: "1 2\n3\n", actual: "  1\n\n2   3", wantPassed: true},
+		{name: "whitespace token differs", problem: whitespace, expected: "1 2 3", actual: "1 2 4", wantMessage: "output differs from the expected answer"},
+		{name: "whitespace token count mismatch", problem: whitespace, expected: "1 2 3", actual: "1 2"},
+		{name: "whitespace both empty", problem: whitespace, expected: "\n", actual: "", wantPassed: true},
+
+		{name: "float exact", problem: float, expected: "0.5 1.25", actual: "0.5 1.25", wantPassed: true},
+		{name: "float within absolute error", problem: float, expected: "1.0", actual: "1.0000009", wantPassed: true},
+		{name: "float within relative error", problem: float, expected: "1000000", actual: "1000000.9", wantPassed: true},
+		{name: "float exceeds both errors", problem: float, expected: "1.0", actual: "1.00001", wantMessage: "token 1: expected 1.0, found 1.00001"},
+		{name: "float relative error exceeded", problem: float, expected: "1000000", actual: "1000002", wantMessage: "exceeds"},
+		{name: "float different notation", problem: float, expected: "0.0000001", actual: "1e-7", wantPassed: true},
+		{name: "float word tokens compared exactly", problem: float, expected: "YES 0.5", actual: "YES 0.5000001", wantPassed: true},
+		{name: "float word token differs", problem: float, expected: "YES", actual: "NO", wantMessage: `token 1: expected "YES", found "NO"`},
+		{name: "float nan is wrong", problem: float, expected: "1", actual: "NaN", wantMessage: "found NaN"},
+		{name: "float infinity is wrong", problem: float, expected: "1", actual: "+Inf", wantMessage: "found +Inf"},
+		{name: "float token count mismatch", problem: float, expected: "1 2", actual: "1", wantMessage: "expected 2 tokens, found 1"},
+		{name: "float default precision", problem: defaultFloat, expected: "2", actual: "2.0000005", wantPassed: true},
+		{name: "float default precision exceeded", problem: defaultFloat, expected: "2", actual: "2.00001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passed, message := checkOutput(tt.problem, tt.expected, tt.actual)
+			if passed != tt.wantPassed {
+				t.Errorf("passed = %v, want %v (message %q)", passed, tt.wantPassed, message)
+			}
+			if passed && message != "" {
+				t.Errorf("message = %q, want empty for a passed case", message)
+			}
+			if tt.wantMessage != "" && !strings.Contains(message, tt.wantMessage) {
+				t.Errorf("message = %q, want it to contain %q", message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestLineMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		problem  *models.Problem
+		expected string
+		actual   string
+		want     bool
+	}{
+		{name: "exact equal", problem: &models.Problem{CheckerMode: "exact"}, expected: "1 2", actual: "1 2", want: true},
+		{name: "exact inner whitespace", problem: &models.Problem{CheckerMode: "exact"}, expected: "1 2", actual: "1  2"},
+		{name: "whitespace inner whitespace", problem: &models.Problem{CheckerMode: "whitespace"}, expected: "1 2", actual: "1  2", want: true},
+		{name: "whitespace token differs", problem: &models.Problem{CheckerMode: "whitespace"}, expected: "1 2", actual: "1 3"},
+		{name: "float within tolerance", problem: &models.Problem{CheckerMode: "float", FloatPrecision: 1e-3}, expected: "3.1416", actual: "3.1419", want: true},
+		{name: "float outside tolerance", problem: &models.Problem{CheckerMode: "float", FloatPrecision: 1e-3}, expected: "3.1416", actual: "3.15"},
+		{name: "float default precision", problem: &models.Problem{CheckerMode: "float"}, expected: "1", actual: "1.001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineMatcher(tt.problem)(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("lineMatcher(%q, %q) = %v, want %v", tt.expected, tt.actual, got, tt.want)
+			}
+		})
+	}
+}
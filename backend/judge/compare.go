@@ -0,0 +1,73 @@
+package judge
+
+import (
+	"strings"
+
+	"backend/api"
+)
+
+const (
+	// maxDiffLines 样例运行中每个样例回传的不同行的最大数量
+	maxDiffLines = 20
+	// maxDiffLineSize 差异中每行回传的最大长度
+	maxDiffLineSize = 256
+)
+
+// outputsMatch 比较用户输出与期望输出，忽略行尾空白和末尾空行
+func outputsMatch(expected, actual string) bool {
+	return normalizeOutput(expected) == normalizeOutput(actual)
+}
+
+// normalizeOutput 去除每行行尾空白及末尾空行
+func normalizeOutput(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffLines 逐行比较期望输出与用户输出，忽略行尾空白与末尾空行，返回 match 判为不同的行，
+// 最多 maxDiffLines 行，过长的行截断为 maxDiffLineSize 字节
+func diffLines(expected, actual string, match func(expected, actual string) bool) []api.DiffLine {
+	e := strings.Split(normalizeOutput(expected), "\n")
+	a := strings.Split(normalizeOutput(actual), "\n")
+	n := len(e)
+	if len(a) > n {
+		n = len(a)
+	}
+
+	var diff []api.DiffLine
+	for i := 0; i < n && len(diff) < maxDiffLines; i++ {
+		var el, al string
+		if i < len(e) {
+			el = e[i]
+		}
+		if i < len(a) {
+			al = a[i]
+		}
+		if (i < len(e)) != (i < len(a)) || !match(el, al) {
+			diff = append(diff, api.DiffLine{Line: i + 1, Expected: truncateLine(el), Actual: truncateLine(al)})
+		}
+	}
+	return diff
+}
+
+// truncateLine 截断差异中过长的行，不附加换行，避免看起来像多出的一行
+func truncateLine(s string) string {
+	if len(s) <= maxDiffLineSize {
+		return s
+	}
+	return s[:maxDiffLineSize] + "..."
+}
+
+// truncate 截断过长文本，用于回传结果
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "\n...(truncated)"
+}
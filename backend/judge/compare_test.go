@@ -0,0 +1,140 @@
+package judge
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"backend/api"
+	"backend/models"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "unchanged", in: "1 2\n3", want: "1 2\n3"},
+		{name: "trailing whitespace", in: "1 2 \t\n3  ", want: "1 2\n3"},
+		{name: "trailing blank lines", in: "1\n\n \n\t\n", want: "1"},
+		{name: "crlf", in: "1\r\n2\r\n", want: "1\n2"},
+		{name: "leading whitespace is kept", in: "  1\n\n2", want: "  1\n\n2"},
+		{name: "only whitespace", in: " \n\n", want: ""},
+		{name: "empty", in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeOutput(tt.in); got != tt.want {
+				t.Errorf("normalizeOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	exact := lineMatcher(&models.Problem{CheckerMode: "exact"})
+	float := lineMatcher(&models.Problem{CheckerMode: "float", FloatPrecision: 1e-6})
+
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		match    func(expected, actual string) bool
+		want     []api.DiffLine
+	}{
+		{name: "identical", expected: "1\n2\n", actual: "1\n2\n", match: exact},
+		{name: "trailing whitespace and blank lines", expected: "1\n2\n", actual: "1  \n2\t\n\n\n", match: exact},
+		{
+			name:     "changed line",
+			expected: "1\n2\n3\n",
+			actual:   "1\n5\n3\n",
+			match:    exact,
+			want:     []api.DiffLine{{Line: 2, Expected: "2", Actual: "5"}},
+		},
+		{
+			name:     "missing lines",
+			expected: "1\n2\n3\n",
+			actual:   "1\n",
+			match:    exact,
+			want:     []api.DiffLine{{Line: 2, Expected: "2"}, {Line: 3, Expected: "3"}},
+		},
+		{
+			name:     "extra lines",
+			expected: "1\n",
+			actual:   "1\n2\n3\n",
+			match:    exact,
+			want:     []api.DiffLine{{Line: 2, Actual: "2"}, {Line: 3, Actual: "3"}},
+		},
+		{
+			name:     "missing blank line",
+			expected: "1\n\n2\n",
+			actual:   "1\n2\n",
+			match:    exact,
+			want:     []api.DiffLine{{Line: 2, Expected: "", Actual: "2"}, {Line: 3, Expected: "2"}},
+		},
+		{
+			name:     "empty output",
+			expected: "1\n",
+			actual:   "",
+			match:    exact,
+			want:     []api.DiffLine{{Line: 1, Expected: "1"}},
+		},
+		{name: "float within tolerance", expected: "0.5\n1.0\n", actual: "0.5000001\n1\n", match: float},
+		{
+			name:     "float outside tolerance",
+			expected: "0.5\n1.0\n",
+			actual:   "0.5000001\n1.1\n",
+			match:    float,
+			want:     []api.DiffLine{{Line: 2, Expected: "1.0", Actual: "1.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.expected, tt.actual, tt.match); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesLimits 差异最多 maxDiffLines 行，过长的行截断且不产生额外的行
+func TestDiffLinesLimits(t *testing.T) {
+	var expected, actual strings.Builder
+	for i := 1; i <= maxDiffLines+10; i++ {
+		fmt.Fprintf(&expected, "%d\n", i)
+		fmt.Fprintf(&actual, "%d\n", -i)
+	}
+	diff := diffLines(expected.String(), actual.String(), lineMatcher(&models.Problem{}))
+	if len(diff) != maxDiffLines {
+		t.Fatalf("got %d diff lines, want %d", len(diff), maxDiffLines)
+	}
+	if last := diff[len(diff)-1]; last.Line != maxDiffLines {
+		t.Errorf("last diff line = %d, want %d", last.Line, maxDiffLines)
+	}
+
+	long := strings.Repeat("x", 5000)
+	diff = diffLines(long+"a", long+"b", lineMatcher(&models.Problem{}))
+	if len(diff) != 1 {
+		t.Fatalf("diffLines = %+v, want one line", diff)
+	}
+	want := strings.Repeat("x", maxDiffLineSize) + "..."
+	if diff[0].Expected != want || diff[0].Actual != want {
+		t.Errorf("long line = (%d bytes, %d bytes), want %d bytes", len(diff[0].Expected), len(diff[0].Actual), len(want))
+	}
+	if strings.Contains(diff[0].Expected, "\n") {
+		t.Error("truncated line must not contain a newline")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("abc", 3); got != "abc" {
+		t.Errorf("truncate = %q, want %q", got, "abc")
+	}
+	if got := truncate("abcd", 3); got != "abc\n...(truncated)" {
+		t.Errorf("truncate = %q, want %q", got, "abc\n...(truncated)")
+	}
+}
@@ -0,0 +1,78 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"backend/api"
+	"backend/models"
+	"gorm.io/gorm"
+)
+
+// heartbeatLoop 周期性发送心跳并刷新管理员设置的状态，直到ctx取消
+func (w *Worker) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.Judge.Heartbeat())
+	defer ticker.Stop()
+
+	for {
+		w.refreshStatus()
+		if err := w.sendHeartbeat(); err != nil {
+			log.Printf("Error sending judge heartbeat: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendHeartbeat 发送一次心跳
+func (w *Worker) sendHeartbeat() error {
+	value, err := json.Marshal(&api.JudgeHeartbeat{
+		Name:        w.name,
+		Host:        w.host,
+		Languages:   SupportedLanguages(),
+		Concurrency: cap(w.slots),
+		Running:     int(atomic.LoadInt32(&w.running)),
+		SentAt:      time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.queue.Publish(context.Background(), w.cfg.Kafka.HeartbeatTopic, w.name, value)
+}
+
+// refreshStatus 从注册表读取本判题机的状态，尚未注册时视为 active
+func (w *Worker) refreshStatus() {
+	var judge models.Judge
+	err := w.db.Select("status").Where("name = ?", w.name).First(&judge).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		judge.Status = "active"
+	case err != nil:
+		// 读取失败时保持原状态
+		log.Printf("Error loading judge status: %v", err)
+		return
+	}
+
+	if old := w.status.Swap(judge.Status); old != judge.Status {
+		log.Printf("Judge %s status: %v -> %s", w.name, old, judge.Status)
+	}
+}
+
+// accepting 判题机是否接收新任务
+func (w *Worker) accepting() bool {
+	return w.status.Load() == "active"
+}
+
+// disabled 判题机是否被禁用。排空时正在执行的任务继续完成，禁用时中止正在执行的任务
+func (w *Worker) disabled() bool {
+	return w.status.Load() == "disabled"
+}
@@ -0,0 +1,149 @@
+package judge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"backend/api"
+	"backend/models"
+	"backend/sandbox"
+)
+
+// interactorMountPoint 交互器目录在沙箱内的挂载点
+const interactorMountPoint = "/interactor"
+
+// runInteractiveCase 运行交互题的单个测试用例：用户程序与交互器的标准输入输出交叉连接，
+// 交互器以 testlib 的方式读取 input.txt、answer.txt 并写出 output.txt，其退出码决定用例结果
+func (w *Worker) runInteractiveCase(dir string, lang Language, problem *models.Problem, limit caseLimit, interactorDir string, tc *models.TestCase) (api.TestCaseResult, error) {
+	timeLimit, memoryMB := limit.Time, limit.MemoryMB
+	memoryLimit := memoryMB * 1024
+
+	interactorWork, err := w.writeHelperFiles("interactor-", map[string]string{
+		"input.txt":  tc.Input,
+		"answer.txt": tc.Output,
+	})
+	if err != nil {
+		return api.TestCaseResult{}, err
+	}
+	defer os.RemoveAll(interactorWork)
+
+	// toInteractor: 用户程序 -> 交互器，toSolution: 交互器 -> 用户程序
+	toInteractorR, toInteractorW, err := os.Pipe()
+	if err != nil {
+		return api.TestCaseResult{}, err
+	}
+	toSolutionR, toSolutionW, err := os.Pipe()
+	if err != nil {
+		toInteractorR.Close()
+		toInteractorW.Close()
+		return api.TestCaseResult{}, err
+	}
+
+	// 每个进程启动后立即关闭父进程持有的管道端，一方退出后另一方才能读到 EOF
+	closeSolutionEnds := func() {
+		toSolutionR.Close()
+		toInteractorW.Close()
+	}
+	closeInteractorEnds := func() {
+		toInteractorR.Close()
+		toSolutionW.Close()
+	}
+
+	solutionWall := 2*timeLimit + time.Second
+	var solution, interactor *runResult
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer closeSolutionEnds()
+		solution = w.execute(&execSpec{
+			Args:        lang.Run,
+			Env:         runEnv,
+			Dir:         dir,
+			Input:       toSolutionR,
+			Output:      toInteractorW,
+			OnStart:     closeSolutionEnds,
+			TimeLimit:   timeLimit,
+			WallLimit:   solutionWall,
+			MemoryLimit: int64(memoryMB) << 20,
+			PidsLimit:   w.cfg.Sandbox.PidsLimit,
+			CPUs:        1,
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		defer closeInteractorEnds()
+		interactor = w.execute(&execSpec{
+			Args:        w.helperArgs(interactorDir, problem.InteractorLanguage, interactorMountPoint, "input.txt", "output.txt", "answer.txt"),
+			Env:         runEnv,
+			Dir:         interactorWork,
+			Writable:    true,
+			Input:       toInteractorR,
+			Output:      toSolutionW,
+			OnStart:     closeInteractorEnds,
+			Mounts:      []sandbox.Mount{{Source: interactorDir, Target: interactorMountPoint}},
+			TimeLimit:   checkerTimeout,
+			WallLimit:   solutionWall + time.Second,
+			MemoryLimit: checkerMemoryLimit,
+			PidsLimit:   w.cfg.Sandbox.PidsLimit,
+		})
+	}()
+	wg.Wait()
+
+	if solution.Err != nil {
+		return api.TestCaseResult{}, solution.Err
+	}
+	if interactor.Err != nil {
+		return api.TestCaseResult{}, interactor.Err
+	}
+
+	// 交互器写出的 output.txt 作为用户输出展示
+	transcript, _ := os.ReadFile(filepath.Join(interactorWork, "output.txt"))
+	caseResult := api.TestCaseResult{
+		TestCaseID:     tc.ID,
+		Input:          truncate(tc.Input, maxReportSize),
+		ExpectedOutput: truncate(tc.Output, maxReportSize),
+		UserOutput:     truncate(string(transcript), maxReportSize),
+		RunTime:        int(solution.CPUTime.Milliseconds()),
+		Memory:         solution.Memory,
+	}
+
+	// 超时、超内存与崩溃优先，其次以交互器的判定为准。
+	// 交互器提前退出后用户程序写管道收到的 SIGPIPE 或因此非零退出不计为运行错误
+	switch {
+	case solution.TimedOut || solution.CPUTime > timeLimit:
+		caseResult.Status = "time_limit_exceeded"
+		return caseResult, nil
+	case solution.OOMKilled || solution.Memory > memoryLimit:
+		caseResult.Status = "memory_limit_exceeded"
+		return caseResult, nil
+	case solution.Signal != "" && solution.Signal != "SIGPIPE":
+		caseResult.Status = "runtime_error"
+		caseResult.Signal = solution.Signal
+		caseResult.ErrorMessage = truncate(signalMessage(solution.Signal, solution.Stderr), maxReportSize)
+		return caseResult, nil
+	}
+
+	passed, message, err := testlibVerdict("interactor", interactor)
+	if err != nil {
+		return api.TestCaseResult{}, err
+	}
+	caseResult.ErrorMessage = message
+	switch {
+	case !passed:
+		caseResult.Status = "failed"
+	case solution.Signal != "":
+		caseResult.Status = "runtime_error"
+		caseResult.Signal = solution.Signal
+		caseResult.ErrorMessage = truncate(signalMessage(solution.Signal, solution.Stderr), maxReportSize)
+	case solution.ExitCode != 0:
+		caseResult.Status = "runtime_error"
+		caseResult.ErrorMessage = truncate(fmt.Sprintf("exit code %d\n%s", solution.ExitCode, solution.Stderr), maxReportSize)
+	default:
+		caseResult.Status = "passed"
+	}
+	return caseResult, nil
+}
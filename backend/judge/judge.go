@@ -0,0 +1,308 @@
+package judge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"backend/api"
+	"backend/common/diagnostic"
+	"backend/common/scoring"
+	"backend/models"
+	"backend/storage"
+)
+
+const (
+	// compileTimeout 编译超时时间
+	compileTimeout = 30 * time.Second
+	// compileMemoryLimit 编译内存限制
+	compileMemoryLimit = 1 << 30
+	// compilePidsLimit 编译器（例如 go build）会启动较多进程与线程
+	compilePidsLimit = 256
+	// maxReportSize 结果中回传的输入输出最大长度
+	maxReportSize = 4096
+	// maxCompileLogSize 结果中回传的编译器输出最大长度
+	maxCompileLogSize = 64 << 10
+)
+
+// Task 判题任务，对应 api.Submit 发送到Kafka的消息
+type Task struct {
+	RunID        string    `json:"run_id,omitempty"` // 评测ID，随结果回传，旧版API服务的任务没有该字段
+	SubmissionID uint      `json:"submission_id"`
+	ProblemID    uint      `json:"problem_id"`
+	UserID       uint      `json:"user_id"`
+	Language     string    `json:"language"`
+	Code         string    `json:"code"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+	TimeLimit    int       `json:"time_limit,omitempty"`   // 该语言实际使用的时间限制，毫秒
+	MemoryLimit  int       `json:"memory_limit,omitempty"` // 该语言实际使用的内存限制，MB
+}
+
+// caseVerdicts 测试用例状态到提交状态的映射
+var caseVerdicts = map[string]string{
+	"failed":                "wrong_answer",
+	"time_limit_exceeded":   "time_limit_exceeded",
+	"memory_limit_exceeded": "memory_limit_exceeded",
+	"runtime_error":         "runtime_error",
+}
+
+// Judge 编译并运行提交的代码，返回判题结果。判题机被禁用时在下一个测试用例前中止评测并返回 nil
+func (w *Worker) Judge(task *Task) *api.JudgeResult {
+	runID := task.RunID
+	if runID == "" {
+		runID = newRunID()
+	}
+	result := &api.JudgeResult{
+		Type:         api.MessageTypeResult,
+		RunID:        runID,
+		SubmissionID: task.SubmissionID,
+		ProblemID:    task.ProblemID,
+	}
+
+	lang, ok := GetLanguage(task.Language)
+	if !ok {
+		result.Status = "compilation_error"
+		result.ErrorMessage = fmt.Sprintf("unsupported language: %s", task.Language)
+		return result
+	}
+
+	// 加载题目与测试用例
+	var problem models.Problem
+	if err := w.db.First(&problem, task.ProblemID).Error; err != nil {
+		return systemError(result, fmt.Errorf("load problem: %w", err))
+	}
+
+	var testCases []models.TestCase
+	if err := w.db.Where("problem_id = ?", problem.ID).Order("sort_order, id").Find(&testCases).Error; err != nil {
+		return systemError(result, fmt.Errorf("load test cases: %w", err))
+	}
+	if len(testCases) == 0 {
+		return systemError(result, fmt.Errorf("problem %d has no test cases", problem.ID))
+	}
+	var subtasks []models.Subtask
+	if err := w.db.Where("problem_id = ?", problem.ID).Find(&subtasks).Error; err != nil {
+		return systemError(result, fmt.Errorf("load subtasks: %w", err))
+	}
+	limit, err := w.taskLimits(task, &problem, lang)
+	if err != nil {
+		return systemError(result, fmt.Errorf("load language limits: %w", err))
+	}
+
+	// 准备工作目录
+	dir, err := os.MkdirTemp(w.cfg.Judge.WorkDir, fmt.Sprintf("submission-%d-", task.SubmissionID))
+	if err != nil {
+		return systemError(result, err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, lang.Source), []byte(task.Code), 0644); err != nil {
+		return systemError(result, err)
+	}
+
+	// 编译
+	if len(lang.Compile) > 0 {
+		w.reportProgress(result, &api.JudgeProgress{Stage: api.StageCompiling, Total: len(testCases)})
+		res := w.compile(dir, lang)
+		if res.Err != nil {
+			return systemError(result, fmt.Errorf("compile: %w", res.Err))
+		}
+		result.CompileLog = truncate(res.Stderr+res.Stdout, maxCompileLogSize)
+		if message, failed := compileFailure(res); failed {
+			result.Status = "compilation_error"
+			result.ErrorMessage = truncate(message, maxReportSize)
+			return result
+		}
+		w.reportProgress(result, &api.JudgeProgress{Stage: api.StageCompiled, Total: len(testCases)})
+	}
+
+	// 准备交互器或自定义检查器
+	helperDir, err := w.prepareProblemHelper(&problem)
+	if err != nil {
+		return systemError(result, err)
+	}
+
+	// 逐个运行测试用例，子任务已不可能得分时跳过其余用例
+	result.Status = "accepted"
+	progress := scoring.NewProgress(subtasks)
+	for i, tc := range testCases {
+		if w.disabled() {
+			return nil
+		}
+		if progress.Skip(tc.SubtaskID) {
+			result.TestCases = append(result.TestCases, api.TestCaseResult{TestCaseID: tc.ID, Status: "skipped"})
+			continue
+		}
+		w.reportProgress(result, &api.JudgeProgress{Stage: api.StageRunning, Case: i + 1, Total: len(testCases)})
+		item, err := w.runTestCase(dir, lang, &problem, limit, helperDir, tc, false)
+		if err != nil {
+			return systemError(result, fmt.Errorf("run test case %d: %w", tc.ID, err))
+		}
+		caseResult := item.TestCaseResult
+		result.TestCases = append(result.TestCases, caseResult)
+		progress.Record(tc.SubtaskID, caseResult.Status == "passed")
+		w.reportProgress(result, &api.JudgeProgress{
+			Stage:    api.StageCaseResult,
+			Case:     i + 1,
+			Total:    len(testCases),
+			TestCase: &caseResult,
+		})
+
+		if caseResult.RunTime > result.RunTime {
+			result.RunTime = caseResult.RunTime
+		}
+		if caseResult.Memory > result.Memory {
+			result.Memory = caseResult.Memory
+		}
+		if caseResult.Status != "passed" && result.Status == "accepted" {
+			result.Status = caseVerdicts[caseResult.Status]
+			result.ErrorMessage = caseResult.ErrorMessage
+		}
+	}
+
+	return result
+}
+
+// compile 在工作目录中编译源码，编译缓存跨提交复用
+func (w *Worker) compile(dir string, lang Language) *runResult {
+	return w.execute(&execSpec{
+		Args:        lang.Compile,
+		Env:         w.compileEnv(),
+		Dir:         dir,
+		Writable:    true,
+		Mounts:      w.cacheMounts(),
+		TimeLimit:   compileTimeout,
+		WallLimit:   compileTimeout,
+		MemoryLimit: compileMemoryLimit,
+		PidsLimit:   compilePidsLimit,
+	})
+}
+
+// compileFailure 编译是否失败，失败时返回错误信息
+func compileFailure(res *runResult) (string, bool) {
+	if !res.TimedOut && !res.OutputExceeded && res.Signal == "" && res.ExitCode == 0 {
+		return "", false
+	}
+	if res.TimedOut {
+		return "compilation timed out", true
+	}
+	return res.Stderr + res.Stdout, true
+}
+
+// runTestCase 在沙箱中运行单个测试用例，CPU时间用于判断超时，墙钟时间适当放宽。
+// helperDir 为交互器或自定义检查器的目录。withDiff 为真且题目使用内置比较方式时，答案错误的用例附带逐行差异，
+// 差异按完整的输出计算，不受回传结果截断的影响
+func (w *Worker) runTestCase(dir string, lang Language, problem *models.Problem, limit caseLimit, helperDir string, tc models.TestCase, withDiff bool) (api.RunCaseResult, error) {
+	if err := w.loadTestData(&tc); err != nil {
+		return api.RunCaseResult{}, err
+	}
+	if problem.JudgeMode == "interactive" {
+		caseResult, err := w.runInteractiveCase(dir, lang, problem, limit, helperDir, &tc)
+		return api.RunCaseResult{TestCaseResult: caseResult}, err
+	}
+
+	timeLimit, memoryMB := limit.Time, limit.MemoryMB
+	memoryLimit := memoryMB * 1024
+
+	res := w.execute(&execSpec{
+		Args:        lang.Run,
+		Env:         runEnv,
+		Dir:         dir,
+		Stdin:       tc.Input,
+		TimeLimit:   timeLimit,
+		MemoryLimit: int64(memoryMB) << 20,
+		PidsLimit:   w.cfg.Sandbox.PidsLimit,
+		CPUs:        1,
+	})
+	if res.Err != nil {
+		return api.RunCaseResult{}, res.Err
+	}
+
+	caseResult := api.RunCaseResult{TestCaseResult: api.TestCaseResult{
+		TestCaseID:     tc.ID,
+		Input:          truncate(tc.Input, maxReportSize),
+		ExpectedOutput: truncate(tc.Output, maxReportSize),
+		UserOutput:     truncate(res.Stdout, maxReportSize),
+		RunTime:        int(res.CPUTime.Milliseconds()),
+		Memory:         res.Memory,
+	}}
+
+	switch {
+	case res.TimedOut || res.CPUTime > timeLimit:
+		caseResult.Status = "time_limit_exceeded"
+	case res.OOMKilled || res.Memory > memoryLimit:
+		caseResult.Status = "memory_limit_exceeded"
+	case res.OutputExceeded:
+		caseResult.Status = "runtime_error"
+		caseResult.ErrorMessage = errOutputLimit.Error()
+	case res.Signal != "":
+		caseResult.Status = "runtime_error"
+		caseResult.Signal = res.Signal
+		caseResult.ErrorMessage = truncate(signalMessage(res.Signal, res.Stderr), maxReportSize)
+	case res.ExitCode != 0:
+		caseResult.Status = "runtime_error"
+		caseResult.ErrorMessage = truncate(fmt.Sprintf("exit code %d\n%s", res.ExitCode, res.Stderr), maxReportSize)
+	default:
+		passed, message, err := w.judgeOutput(problem, helperDir, &tc, res.Stdout)
+		if err != nil {
+			return api.RunCaseResult{}, err
+		}
+		caseResult.Status = "failed"
+		if passed {
+			caseResult.Status = "passed"
+		}
+		caseResult.ErrorMessage = message
+		if !passed && withDiff && problem.CheckerMode != "custom" {
+			caseResult.Diff = diffLines(tc.Output, res.Stdout, lineMatcher(problem))
+		}
+	}
+
+	return caseResult, nil
+}
+
+// signalMessage 程序被信号终止时的错误信息，附带常见信号的说明与标准错误输出
+func signalMessage(signal, stderr string) string {
+	if description := diagnostic.SignalDescription(signal); description != "" {
+		return fmt.Sprintf("killed by signal %s: %s\n%s", signal, description, stderr)
+	}
+	return fmt.Sprintf("killed by signal %s\n%s", signal, stderr)
+}
+
+// loadTestData 从文件存储读取存放在表外的输入输出
+func (w *Worker) loadTestData(tc *models.TestCase) error {
+	if tc.InputFile != "" {
+		data, err := storage.ReadAll(w.store, tc.InputFile)
+		if err != nil {
+			return fmt.Errorf("load input %s: %w", tc.InputFile, err)
+		}
+		tc.Input = string(data)
+	}
+	if tc.OutputFile != "" {
+		data, err := storage.ReadAll(w.store, tc.OutputFile)
+		if err != nil {
+			return fmt.Errorf("load output %s: %w", tc.OutputFile, err)
+		}
+		tc.Output = string(data)
+	}
+	return nil
+}
+
+// newRunID 为没有评测ID的任务生成评测ID，同一提交被重复评测时用于区分各次结果
+func newRunID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// systemError 判题系统内部错误
+func systemError(result *api.JudgeResult, err error) *api.JudgeResult {
+	result.Status = "system_error"
+	result.ErrorMessage = err.Error()
+	result.TestCases = nil
+	return result
+}
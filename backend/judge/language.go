@@ -0,0 +1,47 @@
+package judge
+
+import (
+	"time"
+
+	"backend/api"
+	"backend/common/language"
+	"backend/models"
+)
+
+// Language 判题语言定义，来自配置中的语言注册表
+type Language = language.Language
+
+// GetLanguage 根据语言ID获取语言定义
+func GetLanguage(id string) (Language, bool) {
+	return language.Get(id)
+}
+
+// SupportedLanguages 返回支持的语言ID列表，按配置中的顺序排列
+func SupportedLanguages() []string {
+	return language.IDs()
+}
+
+// isHelperLanguage 语言是否可用于检查器、交互器等辅助程序
+func isHelperLanguage(id string) bool {
+	return language.IsHelper(id)
+}
+
+// caseLimit 运行测试用例的时间限制与内存限制（MB）
+type caseLimit struct {
+	Time     time.Duration
+	MemoryMB int
+}
+
+// taskLimits 判题任务使用的限制。任务中带有提交时计算的限制时直接使用，
+// 旧版本API服务发送的任务没有限制，按题目的语言限制与语言倍数计算
+func (w *Worker) taskLimits(task *Task, problem *models.Problem, lang Language) (caseLimit, error) {
+	timeLimit, memoryLimit := task.TimeLimit, task.MemoryLimit
+	if timeLimit <= 0 || memoryLimit <= 0 {
+		override, err := api.LanguageOverride(w.db, problem.ID, lang.ID)
+		if err != nil {
+			return caseLimit{}, err
+		}
+		timeLimit, memoryLimit = api.EffectiveLimits(problem, lang, override)
+	}
+	return caseLimit{Time: time.Duration(timeLimit) * time.Millisecond, MemoryMB: memoryLimit}, nil
+}
@@ -0,0 +1,147 @@
+package judge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"backend/api"
+	"backend/models"
+)
+
+// maxRunOutputSize 自定义输入运行回传的标准输出与标准错误最大长度
+const maxRunOutputSize = 64 << 10
+
+// RunCode 编译并以用户提供的输入或题目的公开样例运行代码，结果不写入数据库
+func (w *Worker) RunCode(task *api.RunTask) *api.RunResult {
+	result := &api.RunResult{
+		Type:        api.MessageTypeRunResult,
+		RunID:       task.RunID,
+		UserID:      task.UserID,
+		Mode:        task.Mode,
+		TimeLimit:   task.TimeLimit,
+		MemoryLimit: task.MemoryLimit,
+	}
+
+	lang, ok := GetLanguage(task.Language)
+	if !ok {
+		result.Status = "compilation_error"
+		result.CompileLog = fmt.Sprintf("unsupported language: %s", task.Language)
+		return result
+	}
+
+	dir, err := os.MkdirTemp(w.cfg.Judge.WorkDir, "run-")
+	if err != nil {
+		return runSystemError(result, err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, lang.Source), []byte(task.Code), 0644); err != nil {
+		return runSystemError(result, err)
+	}
+
+	if len(lang.Compile) > 0 {
+		res := w.compile(dir, lang)
+		if res.Err != nil {
+			return runSystemError(result, fmt.Errorf("compile: %w", res.Err))
+		}
+		if message, failed := compileFailure(res); failed {
+			result.Status = "compilation_error"
+			result.CompileLog = truncate(message, maxCompileLogSize)
+			return result
+		}
+		result.CompileLog = truncate(res.Stderr+res.Stdout, maxCompileLogSize)
+	}
+
+	if task.Mode == api.RunModeExamples {
+		return w.runExamples(task, result, dir, lang)
+	}
+
+	timeLimit := time.Duration(task.TimeLimit) * time.Millisecond
+	res := w.execute(&execSpec{
+		Args:        lang.Run,
+		Env:         runEnv,
+		Dir:         dir,
+		Stdin:       task.Input,
+		TimeLimit:   timeLimit,
+		MemoryLimit: int64(task.MemoryLimit) << 20,
+		PidsLimit:   w.cfg.Sandbox.PidsLimit,
+		CPUs:        1,
+	})
+	if res.Err != nil {
+		return runSystemError(result, res.Err)
+	}
+
+	result.Stdout = truncate(res.Stdout, maxRunOutputSize)
+	result.Stderr = truncate(res.Stderr, maxRunOutputSize)
+	result.ExitCode = res.ExitCode
+	result.Signal = res.Signal
+	result.RunTime = int(res.CPUTime.Milliseconds())
+	result.Memory = res.Memory
+
+	switch {
+	case res.TimedOut || res.CPUTime > timeLimit:
+		result.Status = "time_limit_exceeded"
+	case res.OOMKilled || res.Memory > task.MemoryLimit*1024:
+		result.Status = "memory_limit_exceeded"
+	case res.OutputExceeded:
+		result.Status = "runtime_error"
+		result.Stderr = truncate(errOutputLimit.Error()+"\n"+res.Stderr, maxRunOutputSize)
+	case res.Signal != "":
+		result.Status = "runtime_error"
+		result.Stderr = truncate(signalMessage(res.Signal, res.Stderr), maxRunOutputSize)
+	case res.ExitCode != 0:
+		result.Status = "runtime_error"
+	default:
+		result.Status = "finished"
+	}
+	return result
+}
+
+// runExamples 以题目的公开样例运行已编译的代码，按题目的检查方式判定。使用内置比较方式的题目中答案错误的样例附带逐行差异，
+// 按题目的比较方式逐行比较；自定义检查器与交互题的判定与参考输出无关，不附带差异
+func (w *Worker) runExamples(task *api.RunTask, result *api.RunResult, dir string, lang Language) *api.RunResult {
+	var problem models.Problem
+	if err := w.db.First(&problem, task.ProblemID).Error; err != nil {
+		return runSystemError(result, fmt.Errorf("load problem: %w", err))
+	}
+	var examples []models.TestCase
+	if err := w.db.Where("problem_id = ? AND is_example = ? AND is_hidden = ?", problem.ID, true, false).
+		Order("sort_order, id").Find(&examples).Error; err != nil {
+		return runSystemError(result, fmt.Errorf("load examples: %w", err))
+	}
+	helperDir, err := w.prepareProblemHelper(&problem)
+	if err != nil {
+		return runSystemError(result, err)
+	}
+
+	limit := caseLimit{Time: time.Duration(task.TimeLimit) * time.Millisecond, MemoryMB: task.MemoryLimit}
+	result.Status = "accepted"
+	for _, tc := range examples {
+		item, err := w.runTestCase(dir, lang, &problem, limit, helperDir, tc, true)
+		if err != nil {
+			return runSystemError(result, fmt.Errorf("run example %d: %w", tc.ID, err))
+		}
+		result.Cases = append(result.Cases, item)
+		caseResult := item.TestCaseResult
+
+		if caseResult.RunTime > result.RunTime {
+			result.RunTime = caseResult.RunTime
+		}
+		if caseResult.Memory > result.Memory {
+			result.Memory = caseResult.Memory
+		}
+		if caseResult.Status != "passed" && result.Status == "accepted" {
+			result.Status = caseVerdicts[caseResult.Status]
+		}
+	}
+	return result
+}
+
+// runSystemError 判题系统自身出错
+func runSystemError(result *api.RunResult, err error) *api.RunResult {
+	result.Status = "system_error"
+	result.Stderr = err.Error()
+	return result
+}
@@ -0,0 +1,218 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"backend/sandbox"
+	"golang.org/x/sys/unix"
+)
+
+// maxOutputSize 程序输出上限，超过视为运行错误
+const maxOutputSize = 16 << 20
+
+// errOutputLimit 输出超限错误
+var errOutputLimit = errors.New("output limit exceeded")
+
+// execSpec 一次进程执行的参数
+type execSpec struct {
+	Args        []string
+	Env         []string
+	Dir         string
+	Writable    bool // 工作目录是否可写
+	Stdin       string
+	TimeLimit   time.Duration // CPU时间限制
+	WallLimit   time.Duration // 墙钟时间限制，为0时取CPU时间限制的两倍加一秒
+	MemoryLimit int64         // 字节
+	PidsLimit   int
+	CPUs        float64
+	Mounts      []sandbox.Mount // 额外挂载，仅沙箱模式生效
+
+	// 交互题中标准输入输出直接连接到管道，设置后忽略 Stdin，结果中没有 Stdout
+	Input   io.Reader
+	Output  io.Writer
+	OnStart func() // 进程启动后调用
+}
+
+// stdio 返回进程的标准输入与标准输出
+func (s *execSpec) stdio(stdout *limitedBuffer) (io.Reader, io.Writer) {
+	var stdin io.Reader = strings.NewReader(s.Stdin)
+	if s.Input != nil {
+		stdin = s.Input
+	}
+	if s.Output != nil {
+		return stdin, s.Output
+	}
+	return stdin, stdout
+}
+
+// runResult 单次进程运行结果
+type runResult struct {
+	Stdout         string
+	Stderr         string
+	ExitCode       int
+	Signal         string
+	TimedOut       bool
+	OOMKilled      bool
+	OutputExceeded bool
+	RunTime        time.Duration // 墙钟时间
+	CPUTime        time.Duration
+	Memory         int   // KB
+	Err            error // 判题系统自身的错误，例如沙箱创建失败
+}
+
+// limitedBuffer 带上限的输出缓冲区
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		b.exceeded = true
+		return 0, errOutputLimit
+	}
+	return b.buf.Write(p)
+}
+
+// runEnv 运行用户程序时使用的最小环境变量
+var runEnv = []string{"PATH=/usr/local/go/bin:/usr/local/bin:/usr/bin:/bin", "LANG=C.UTF-8"}
+
+// cacheMountPoint 编译缓存目录在沙箱内的挂载点
+const cacheMountPoint = "/cache"
+
+// compileEnv 编译时使用的环境变量，编译缓存跨提交复用
+func (w *Worker) compileEnv() []string {
+	cacheDir := w.cacheDir
+	if w.cfg.Sandbox.Enabled {
+		cacheDir = cacheMountPoint
+	}
+	return append([]string{
+		"HOME=/tmp",
+		"GOCACHE=" + filepath.Join(cacheDir, "go-build"),
+		"GOPATH=/tmp/go",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+	}, runEnv...)
+}
+
+// cacheMounts 编译阶段挂载的缓存目录
+func (w *Worker) cacheMounts() []sandbox.Mount {
+	return []sandbox.Mount{{Source: w.cacheDir, Target: cacheMountPoint, Writable: true}}
+}
+
+// execute 运行进程，启用沙箱时在沙箱中执行
+func (w *Worker) execute(s *execSpec) *runResult {
+	if s.WallLimit == 0 {
+		s.WallLimit = 2*s.TimeLimit + time.Second
+	}
+	if !w.cfg.Sandbox.Enabled {
+		return runCommand(s)
+	}
+
+	stdout := &limitedBuffer{limit: maxOutputSize}
+	stderr := &limitedBuffer{limit: maxOutputSize}
+	stdin, output := s.stdio(stdout)
+	res, err := sandbox.Run(&sandbox.Config{
+		Args:          s.Args,
+		Env:           s.Env,
+		Dir:           s.Dir,
+		Writable:      s.Writable,
+		Stdin:         stdin,
+		Stdout:        output,
+		Stderr:        stderr,
+		OnStart:       s.OnStart,
+		TimeLimit:     s.TimeLimit,
+		WallLimit:     s.WallLimit,
+		MemoryLimit:   s.MemoryLimit,
+		PidsLimit:     s.PidsLimit,
+		CPUs:          s.CPUs,
+		OutputLimit:   int64(w.cfg.Sandbox.OutputLimit) << 20,
+		UID:           w.cfg.Sandbox.UID,
+		GID:           w.cfg.Sandbox.GID,
+		CgroupRoot:    w.cfg.Sandbox.CgroupRoot,
+		ReadOnlyPaths: w.cfg.Sandbox.ReadOnlyPaths,
+		Mounts:        s.Mounts,
+	})
+	if err != nil {
+		return &runResult{Err: err}
+	}
+
+	return &runResult{
+		Stdout:         stdout.buf.String(),
+		Stderr:         stderr.buf.String(),
+		ExitCode:       res.ExitCode,
+		Signal:         res.Signal,
+		TimedOut:       res.TimedOut,
+		OOMKilled:      res.OOMKilled,
+		OutputExceeded: stdout.exceeded || stderr.exceeded,
+		RunTime:        res.WallTime,
+		CPUTime:        res.CPUTime,
+		Memory:         int(res.Memory),
+	}
+}
+
+// runCommand 不经沙箱直接运行命令，仅用于未启用沙箱的开发环境
+func runCommand(s *execSpec) *runResult {
+	ctx, cancel := context.WithTimeout(context.Background(), s.WallLimit)
+	defer cancel()
+
+	// 相对路径的可执行文件以工作目录为基准
+	name := s.Args[0]
+	if strings.HasPrefix(name, "./") {
+		name = filepath.Join(s.Dir, name)
+	}
+
+	cmd := exec.CommandContext(ctx, name, s.Args[1:]...)
+	cmd.Dir = s.Dir
+	cmd.Env = s.Env
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	stdout := &limitedBuffer{limit: maxOutputSize}
+	stderr := &limitedBuffer{limit: maxOutputSize}
+	cmd.Stdin, cmd.Stdout = s.stdio(stdout)
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		if s.OnStart != nil {
+			s.OnStart()
+		}
+		err = cmd.Wait()
+	}
+	result := &runResult{
+		Stdout:         stdout.buf.String(),
+		Stderr:         stderr.buf.String(),
+		RunTime:        time.Since(start),
+		TimedOut:       ctx.Err() == context.DeadlineExceeded,
+		OutputExceeded: stdout.exceeded || stderr.exceeded,
+	}
+
+	if cmd.ProcessState == nil {
+		result.Err = err
+		return result
+	}
+
+	result.ExitCode = cmd.ProcessState.ExitCode()
+	result.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	if usage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		result.Memory = int(usage.Maxrss)
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() && !result.TimedOut {
+		result.Signal = unix.SignalName(status.Signal())
+	}
+
+	return result
+}
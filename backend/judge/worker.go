@@ -0,0 +1,334 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"backend/api"
+	"backend/config"
+	"backend/queue"
+	"backend/storage"
+	"gorm.io/gorm"
+)
+
+// Worker 判题机，消费判题任务并回传判题结果
+type Worker struct {
+	db       *gorm.DB
+	cfg      *config.Config
+	queue    queue.Queue       // 判题任务、结果与心跳的消息队列
+	store    storage.FileStore // 测试数据文件存储
+	cacheDir string            // 编译缓存目录，跨提交复用
+
+	name    string               // 注册表中的判题机名称
+	host    string               // 主机名
+	slots   chan struct{}        // 并发判题名额
+	running int32                // 正在判题的任务数
+	waiting [priorityCount]int32 // 各优先级等待名额的任务数，有更高优先级的任务等待时低优先级任务让出名额
+	status  atomic.Value         // 管理员设置的状态：active, draining, disabled
+}
+
+// NewWorker 创建判题机实例，q 由调用方创建与关闭
+func NewWorker(db *gorm.DB, cfg *config.Config, q queue.Queue) (*Worker, error) {
+	cacheDir, err := prepareWorkDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := storage.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Judge.Name
+	if name == "" {
+		name = host
+	}
+	concurrency := cfg.Judge.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	w := &Worker{
+		db:       db,
+		cfg:      cfg,
+		queue:    q,
+		store:    store,
+		cacheDir: cacheDir,
+		name:     name,
+		host:     host,
+		slots:    make(chan struct{}, concurrency),
+	}
+	w.status.Store("active")
+	return w, nil
+}
+
+// prepareWorkDir 创建工作目录与编译缓存目录，缓存目录需要对沙箱用户可写
+func prepareWorkDir(cfg *config.Config) (string, error) {
+	cacheDir := filepath.Join(cfg.Judge.WorkDir, "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+	if cfg.Sandbox.Enabled && os.Getuid() == 0 {
+		if err := os.Chown(cacheDir, cfg.Sandbox.UID, cfg.Sandbox.GID); err != nil {
+			return "", err
+		}
+	}
+	return cacheDir, nil
+}
+
+// Run 持续消费判题任务并发送心跳，直到ctx取消。
+// 判题机被排空或禁用时离开消费者组，分区由其他判题机接管，恢复后重新加入。
+// 排空时等待正在执行的判题完成并发送结果；禁用时中止正在执行的判题，任务不确认，由其他判题机重新评测。
+func (w *Worker) Run(ctx context.Context) error {
+	w.refreshStatus()
+	go w.heartbeatLoop(ctx)
+
+	for w.waitAccepting(ctx) {
+		if err := w.consume(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// statusPollInterval 检查状态变化的间隔
+const statusPollInterval = time.Second
+
+// errJudgeDisabled 判题机被禁用，正在执行的判题被中止
+var errJudgeDisabled = errors.New("judge disabled")
+
+// waitAccepting 等待判题机恢复接收任务，ctx取消时返回false
+func (w *Worker) waitAccepting(ctx context.Context) bool {
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	for !w.accepting() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// consume 加入消费者组消费判题任务，直到ctx取消或判题机不再接收任务
+func (w *Worker) consume(ctx context.Context) error {
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(statusPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-consumeCtx.Done():
+				return
+			case <-ticker.C:
+				if !w.accepting() {
+					log.Printf("Judge %s stopped accepting tasks", w.name)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	// Consume 会等待正在执行的判题完成后返回
+	return w.queue.Consume(consumeCtx, "judge-worker-group", w.topics(), w.handle)
+}
+
+// 判题任务优先级，与API服务发件箱中的优先级一致，数值越小越优先
+const (
+	priorityContest = iota // 比赛中的提交
+	priorityNormal         // 普通提交
+	priorityRejudge        // 重新评测
+	priorityCount
+)
+
+// topics 消费的判题任务主题，包括比赛、普通提交、重新评测与自定义输入运行
+func (w *Worker) topics() []string {
+	var topics []string
+	seen := make(map[string]bool)
+	for _, topic := range []string{w.cfg.Kafka.ContestTopic, w.cfg.Kafka.Topic, w.cfg.Kafka.RejudgeTopic, w.cfg.Kafka.RunTopic} {
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// priority 主题对应的优先级，未单独配置主题的优先级与自定义输入运行按普通提交处理
+func (w *Worker) priority(topic string) int {
+	switch topic {
+	case w.cfg.Kafka.Topic:
+		return priorityNormal
+	case w.cfg.Kafka.ContestTopic:
+		return priorityContest
+	case w.cfg.Kafka.RejudgeTopic:
+		return priorityRejudge
+	default:
+		return priorityNormal
+	}
+}
+
+// handle 评测一个判题任务并发送结果，结果送达后任务才被确认
+func (w *Worker) handle(ctx context.Context, message *queue.Message) error {
+	if message.Topic == w.cfg.Kafka.RunTopic {
+		return w.handleRun(ctx, message)
+	}
+
+	var task Task
+	if err := json.Unmarshal(message.Value, &task); err != nil {
+		log.Printf("Error unmarshaling judge task: %v", err)
+		return nil
+	}
+
+	if !w.acquireSlot(ctx, w.priority(message.Topic)) {
+		// 消费结束，未处理的任务会被重新投递
+		return ctx.Err()
+	}
+	result := w.judgeWithSlot(&task)
+	if result == nil {
+		// 判题机被禁用，不确认任务，离开消费者组后由其他判题机重新评测
+		log.Printf("Judging submission %d aborted: judge %s disabled", task.SubmissionID, w.name)
+		return errJudgeDisabled
+	}
+
+	if err := w.publishResult(result); err != nil {
+		// 结果未能送达时不确认任务，等待重新投递
+		log.Printf("Error publishing judge result: %v", err)
+		return err
+	}
+	return nil
+}
+
+// handleRun 运行一个自定义输入任务并发送结果，结果以运行ID为键发送到结果主题
+func (w *Worker) handleRun(ctx context.Context, message *queue.Message) error {
+	var task api.RunTask
+	if err := json.Unmarshal(message.Value, &task); err != nil {
+		log.Printf("Error unmarshaling run task: %v", err)
+		return nil
+	}
+
+	if !w.acquireSlot(ctx, priorityNormal) {
+		return ctx.Err()
+	}
+	atomic.AddInt32(&w.running, 1)
+	result := w.RunCode(&task)
+	atomic.AddInt32(&w.running, -1)
+	<-w.slots
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := w.queue.Publish(context.Background(), w.cfg.Kafka.ResultTopic, task.RunID, value); err != nil {
+		log.Printf("Error publishing run result: %v", err)
+		return err
+	}
+	return nil
+}
+
+// lowPriorityPollInterval 非最高优先级的任务等待名额时的检查间隔
+const lowPriorityPollInterval = 100 * time.Millisecond
+
+// acquireSlot 获取一个并发判题名额。比赛任务排队等待；其他任务只在没有更高优先级的任务等待时获取空闲名额，
+// 因此大量重新评测不会延迟普通提交，普通提交不会延迟比赛。ctx取消时返回false
+func (w *Worker) acquireSlot(ctx context.Context, priority int) bool {
+	atomic.AddInt32(&w.waiting[priority], 1)
+	defer atomic.AddInt32(&w.waiting[priority], -1)
+
+	if priority == priorityContest {
+		select {
+		case w.slots <- struct{}{}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	ticker := time.NewTicker(lowPriorityPollInterval)
+	defer ticker.Stop()
+	for {
+		if !w.higherWaiting(priority) {
+			select {
+			case w.slots <- struct{}{}:
+				return true
+			default:
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// higherWaiting 是否有比 priority 优先级更高的任务在等待名额
+func (w *Worker) higherWaiting(priority int) bool {
+	for p := 0; p < priority; p++ {
+		if atomic.LoadInt32(&w.waiting[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// judgeWithSlot 使用已获取的并发名额判题，完成后释放，各分区的任务并行处理。判题被中止时返回 nil
+func (w *Worker) judgeWithSlot(task *Task) *api.JudgeResult {
+	atomic.AddInt32(&w.running, 1)
+	defer func() {
+		atomic.AddInt32(&w.running, -1)
+		<-w.slots
+	}()
+
+	log.Printf("Judging submission %d (problem %d, %s)", task.SubmissionID, task.ProblemID, task.Language)
+	result := w.Judge(task)
+	if result != nil {
+		log.Printf("Submission %d judged: %s", task.SubmissionID, result.Status)
+	}
+	return result
+}
+
+// publishResult 将判题结果发送到结果主题
+func (w *Worker) publishResult(result *api.JudgeResult) error {
+	return w.publish(result.SubmissionID, result)
+}
+
+// publish 以提交ID为键发送消息到结果主题，同一提交的进度与结果进入同一分区，保持顺序
+func (w *Worker) publish(submissionID uint, message interface{}) error {
+	value, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	key := strconv.FormatUint(uint64(submissionID), 10)
+	return w.queue.Publish(context.Background(), w.cfg.Kafka.ResultTopic, key, value)
+}
+
+// reportProgress 发送评测 result 的进度，最终结果包含全部用例结果，因此发送失败不影响判题
+func (w *Worker) reportProgress(result *api.JudgeResult, progress *api.JudgeProgress) {
+	if w.queue == nil {
+		return
+	}
+	progress.Type = api.MessageTypeProgress
+	progress.RunID = result.RunID
+	progress.SubmissionID = result.SubmissionID
+	if err := w.publish(progress.SubmissionID, progress); err != nil {
+		log.Printf("Error publishing judge progress: %v", err)
+	}
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"backend/api"
 	"backend/common/database"
 	"backend/config"
+	"backend/judge"
 	"backend/models"
+	"backend/queue"
+	"backend/sandbox"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 func main() {
+	// 内嵌判题机的沙箱初始化进程在此接管，不会返回
+	sandbox.Init()
+
 	// 加载配置
 	cfg, err := config.LoadConfig("./config.json")
 	if err != nil {
@@ -25,6 +32,20 @@ func main() {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 
+	// 初始化Redis，用于缓存统计数据
+	if _, err := database.InitRedis(cfg); err != nil {
+		log.Printf("初始化Redis失败: %v", err)
+		log.Printf("系统将在无缓存模式下运行")
+	}
+
+	// 测试用例结果按提交与用例唯一，创建唯一索引前清理重复投递产生的重复结果，保留最新一条
+	if db.Migrator().HasTable(&models.TestCaseResult{}) && !db.Migrator().HasIndex(&models.TestCaseResult{}, "idx_submission_test_case") {
+		if err := db.Exec("DELETE FROM test_case_results a USING test_case_results b " +
+			"WHERE a.submission_id = b.submission_id AND a.test_case_id = b.test_case_id AND a.id < b.id").Error; err != nil {
+			log.Fatalf("清理重复的测试用例结果失败，无法创建唯一索引 idx_submission_test_case: %v", err)
+		}
+	}
+
 	// 自动迁移数据库模型
 	if err := db.AutoMigrate(
 		&models.User{},
@@ -33,6 +54,15 @@ func main() {
 		&models.TestCase{},
 		&models.TestCaseResult{},
 		&models.Judge{},
+		&models.TestDataVersion{},
+		&models.Contest{},
+		&models.ContestProblem{},
+		&models.ContestRegistration{},
+		&models.Subtask{},
+		&models.OutboxMessage{},
+		&models.RejudgeBatch{},
+		&models.SubmissionHistory{},
+		&models.ProblemLanguageLimit{},
 	); err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
 	}
@@ -40,18 +70,39 @@ func main() {
 	// 重置 submissions 表的自增序列
 	db.Exec("SELECT setval(pg_get_serial_sequence('submissions', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM submissions")
 
-	// 初始化Kafka
-	if err := api.InitKafka(cfg); err != nil {
-		log.Printf("初始化Kafka失败: %v", err)
-		log.Printf("系统将在无Kafka模式下运行")
+	// 初始化测试数据存储
+	if err := api.InitStorage(cfg); err != nil {
+		log.Printf("初始化测试数据存储失败: %v", err)
+		log.Printf("系统将无法上传测试数据压缩包")
+	}
+
+	// 初始化消息队列
+	if err := api.InitQueue(cfg); err != nil {
+		log.Fatalf("初始化消息队列失败: %v", err)
+	}
+
+	// 启动发件箱发送协程，提交的判题任务经由发件箱发送到消息队列
+	api.InitOutboxDispatcher(db)
+
+	// 进程内队列或配置了 judge.embedded 时在本进程中运行判题机
+	if cfg.Judge.Embedded || api.JudgeQueue.Driver() == queue.DriverMemory {
+		startEmbeddedJudge(db, cfg)
 	}
 
+	// 启动超时提交回收协程
+	api.InitSubmissionReaper(db)
+
 	// 初始化判题结果消费者
 	if err := api.InitJudgeResultConsumer(db); err != nil {
 		log.Printf("初始化判题结果消费者失败: %v", err)
 		log.Printf("系统将在无判题结果消费模式下运行")
 	}
 
+	// 初始化判题机心跳消费者
+	if err := api.InitJudgeHeartbeatConsumer(db); err != nil {
+		log.Printf("初始化判题机心跳消费者失败: %v", err)
+	}
+
 	// 初始化路由
 	r := initRouter(db)
 
@@ -63,6 +114,23 @@ func main() {
 	}
 }
 
+// startEmbeddedJudge 在API服务进程内启动判题机
+func startEmbeddedJudge(db *gorm.DB, cfg *config.Config) {
+	worker, err := judge.NewWorker(db, cfg, api.JudgeQueue)
+	if err != nil {
+		log.Printf("初始化内嵌判题机失败: %v", err)
+		log.Printf("提交将等待独立运行的判题机评测")
+		return
+	}
+
+	go func() {
+		log.Printf("内嵌判题机启动，消费主题 %s（%s）", cfg.Kafka.Topic, api.JudgeQueue.Driver())
+		if err := worker.Run(context.Background()); err != nil {
+			log.Printf("内嵌判题机运行失败: %v", err)
+		}
+	}()
+}
+
 // initRouter 初始化路由
 func initRouter(db *gorm.DB) *gin.Engine {
 	r := gin.Default()
@@ -78,7 +146,7 @@ func initRouter(db *gorm.DB) *gin.Engine {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Requested-With")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Cache-Control, Content-Language, Content-Type, Retry-After")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		// 如果是OPTIONS请求，直接返回204
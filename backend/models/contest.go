@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Contest 比赛实体模型
+type Contest struct {
+	gorm.Model
+	Title         string           `json:"title" gorm:"not null"`
+	Description   string           `json:"description" gorm:"type:text"`
+	StartTime     time.Time        `json:"start_time" gorm:"not null"`
+	EndTime       time.Time        `json:"end_time" gorm:"not null"`
+	RuleType      string           `json:"rule_type" gorm:"default:'icpc'"` // icpc, oi
+	FreezeMinutes int              `json:"freeze_minutes"`                  // 比赛结束前封榜的分钟数，0 表示不封榜
+	UnfrozenAt    *time.Time       `json:"unfrozen_at"`                     // 赛后解除封榜的时间
+	CreatedBy     uint             `json:"created_by"`
+	Problems      []ContestProblem `json:"problems,omitempty" gorm:"foreignKey:ContestID"`
+}
+
+// TableName 指定表名
+func (Contest) TableName() string {
+	return "contests"
+}
+
+// FreezeTime 封榜开始时间，不封榜时返回 false
+func (c *Contest) FreezeTime() (time.Time, bool) {
+	if c.FreezeMinutes <= 0 {
+		return time.Time{}, false
+	}
+	return c.EndTime.Add(-time.Duration(c.FreezeMinutes) * time.Minute), true
+}
+
+// IsFrozen 当前时刻普通选手看到的榜单是否处于封榜状态
+func (c *Contest) IsFrozen(now time.Time) bool {
+	freezeTime, ok := c.FreezeTime()
+	return ok && c.UnfrozenAt == nil && !now.Before(freezeTime)
+}
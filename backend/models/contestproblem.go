@@ -0,0 +1,19 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// ContestProblem 比赛题目，Label 为题号，例如 A、B
+type ContestProblem struct {
+	gorm.Model
+	ContestID uint     `json:"contest_id" gorm:"not null;uniqueIndex:idx_contest_label"`
+	ProblemID uint     `json:"problem_id" gorm:"not null"`
+	Label     string   `json:"label" gorm:"not null;uniqueIndex:idx_contest_label"`
+	Problem   *Problem `json:"problem,omitempty" gorm:"foreignKey:ProblemID"`
+}
+
+// TableName 指定表名
+func (ContestProblem) TableName() string {
+	return "contest_problems"
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// ContestRegistration 比赛报名记录
+type ContestRegistration struct {
+	gorm.Model
+	ContestID uint `json:"contest_id" gorm:"not null;uniqueIndex:idx_contest_user"`
+	UserID    uint `json:"user_id" gorm:"not null;uniqueIndex:idx_contest_user"`
+}
+
+// TableName 指定表名
+func (ContestRegistration) TableName() string {
+	return "contest_registrations"
+}
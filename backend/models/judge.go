@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Judge 判题机实体模型
+type Judge struct {
+	gorm.Model
+	Name          string    `json:"name" gorm:"unique;not null"`
+	Host          string    `json:"host"`
+	Languages     string    `json:"languages"`                      // 支持的语言，逗号分隔
+	Concurrency   int       `json:"concurrency" gorm:"default:1"`   // 最大并发判题数
+	Running       int       `json:"running"`                        // 正在判题的任务数
+	Status        string    `json:"status" gorm:"default:'active'"` // active, draining, disabled
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	Online        bool      `json:"online" gorm:"-"` // 根据最近心跳计算，不落库
+}
+
+// TableName 指定表名
+func (Judge) TableName() string {
+	return "judges"
+}
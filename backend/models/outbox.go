@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxMessage 待发送到消息队列的消息，与业务数据在同一事务中写入，由后台任务发送
+type OutboxMessage struct {
+	gorm.Model
+	Topic         string     `json:"topic" gorm:"not null"`
+	Key           string     `json:"key"`
+	Payload       string     `json:"payload" gorm:"type:text;not null"`
+	Attempts      int        `json:"attempts" gorm:"default:0"`   // 发送失败次数
+	LastError     string     `json:"last_error" gorm:"type:text"` // 最近一次发送失败的原因
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	SentAt        *time.Time `json:"sent_at" gorm:"index"` // 为空表示尚未发送
+
+	Priority     int  `json:"priority" gorm:"index"`          // 发送顺序，数值越小越先发送：0 比赛，1 普通，2 重新评测
+	UserID       uint `json:"user_id" gorm:"default:0;index"` // 受用户公平上限约束的判题任务所属用户，0 表示不受约束
+	SubmissionID uint `json:"submission_id" gorm:"default:0;index"`
+}
+
+// TableName 指定表名
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
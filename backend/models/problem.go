@@ -16,8 +16,16 @@ type Problem struct {
 	MemoryLimit int       `json:"memory_limit" gorm:"default:256"` // MB
 	Tags        string    `json:"tags"`
 	CreatedBy   uint      `json:"created_by"`
+	CheckerMode     string  `json:"checker_mode" gorm:"default:'exact'"`      // exact, whitespace, float, custom
+	FloatPrecision  float64 `json:"float_precision" gorm:"default:0.000001"` // float 模式允许的绝对或相对误差
+	CheckerLanguage string  `json:"checker_language"`                       // custom 模式检查器语言：cpp, go
+	CheckerCode     string  `json:"checker_code,omitempty" gorm:"type:text"` // custom 模式检查器源码，testlib 兼容
+	JudgeMode          string `json:"judge_mode" gorm:"default:'standard'"`      // standard, interactive
+	InteractorLanguage string `json:"interactor_language"`                      // interactive 模式交互器语言：cpp, go
+	InteractorCode     string `json:"interactor_code,omitempty" gorm:"type:text"` // interactive 模式交互器源码，testlib 兼容
 	UpdatedAt   time.Time `json:"updated_at"`
 	TestCases   []TestCase `json:"test_cases,omitempty" gorm:"foreignKey:ProblemID"`
+	Subtasks    []Subtask `json:"subtasks,omitempty" gorm:"foreignKey:ProblemID"`
 	Submissions []Submission `json:"submissions,omitempty" gorm:"foreignKey:ProblemID"`
 }
 
@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProblemLanguageLimit 题目针对某种语言的时间与内存限制，覆盖按语言倍数计算的限制
+type ProblemLanguageLimit struct {
+	gorm.Model
+	ProblemID   uint   `json:"problem_id" gorm:"not null;uniqueIndex:idx_problem_language_limit"`
+	Language    string `json:"language" gorm:"not null;uniqueIndex:idx_problem_language_limit"`
+	TimeLimit   int    `json:"time_limit"`   // 毫秒，0 表示按语言倍数计算
+	MemoryLimit int    `json:"memory_limit"` // MB，0 表示按语言倍数计算
+}
+
+// TableName 指定表名
+func (ProblemLanguageLimit) TableName() string {
+	return "problem_language_limits"
+}
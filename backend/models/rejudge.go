@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RejudgeBatch 一次重新评测操作
+type RejudgeBatch struct {
+	gorm.Model
+	Scope        string     `json:"scope" gorm:"not null"` // submission, problem, user
+	SubmissionID uint       `json:"submission_id,omitempty"`
+	ProblemID    uint       `json:"problem_id,omitempty"`
+	UserID       uint       `json:"user_id,omitempty"`
+	OnlyAccepted bool       `json:"only_accepted"`
+	From         *time.Time `json:"from,omitempty"` // 只重新评测该时间之后的提交
+	To           *time.Time `json:"to,omitempty"`   // 只重新评测该时间之前的提交
+	Total        int        `json:"total"`
+	CreatedBy    uint       `json:"created_by"`
+}
+
+// TableName 指定表名
+func (RejudgeBatch) TableName() string {
+	return "rejudge_batches"
+}
+
+// SubmissionHistory 提交被重新评测前的判题结果
+type SubmissionHistory struct {
+	gorm.Model
+	SubmissionID   uint      `json:"submission_id" gorm:"not null;index"`
+	RejudgeBatchID uint      `json:"rejudge_batch_id" gorm:"not null;index"`
+	Status         string    `json:"status"`
+	Score          float64   `json:"score"`
+	RunTime        int       `json:"run_time"` // 毫秒
+	Memory         int       `json:"memory"`   // KB
+	ErrorMessage   string    `json:"error_message" gorm:"type:text"`
+	JudgeRunID     string    `json:"judge_run_id"`
+	JudgedAt       time.Time `json:"judged_at"`
+}
+
+// TableName 指定表名
+func (SubmissionHistory) TableName() string {
+	return "submission_histories"
+}
@@ -12,14 +12,20 @@ type Submission struct {
 	gorm.Model
 	ProblemID       uint             `json:"problem_id" gorm:"not null"`
 	UserID          uint             `json:"user_id" gorm:"not null"`
+	ContestID       uint             `json:"contest_id" gorm:"default:0;index"` // 0 表示非比赛提交
 	Language        string           `json:"language" gorm:"not null"`
 	Code            string           `json:"code" gorm:"type:text;not null"`
 	Status          string           `json:"status" gorm:"default:'pending'"` // pending, judging, accepted, wrong_answer, etc.
 	RunTime         int              `json:"run_time"`                        // 毫秒
 	Memory          int              `json:"memory"`                          // KB
+	Score           float64          `json:"score"`                           // 有子任务时为子任务得分之和，否则按测试用例权重计算，满分 100
 	SubmittedAt     time.Time        `json:"submitted_at" gorm:"autoCreateTime"`
 	JudgedAt        time.Time        `json:"judged_at"`
+	JudgeRunID      string           `json:"judge_run_id"`          // 评测ID，等待评测时为最近一次发送的判题任务的ID，出结果后为产生最终结果的评测ID
+	RequeueCount    int              `json:"requeue_count"`         // 超时后重新发送判题任务的次数
+	RejudgedAt      *time.Time       `json:"rejudged_at,omitempty"` // 最近一次重新评测的时间
 	ErrorMessage    string           `json:"error_message" gorm:"type:text"`
+	CompileLog      string           `json:"-" gorm:"type:text"` // 编译器输出，通过 /submissions/:id/compile-log 获取
 	TestCaseResults []TestCaseResult `json:"test_case_results,omitempty" gorm:"foreignKey:SubmissionID"`
 }
 
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Subtask 子任务实体模型，一组测试用例共同计分
+type Subtask struct {
+	gorm.Model
+	ProblemID   uint    `json:"problem_id" gorm:"not null;index"`
+	Name        string  `json:"name" gorm:"not null"`
+	Score       float64 `json:"score"`                            // 子任务分值
+	Aggregation string  `json:"aggregation" gorm:"default:'min'"` // min：全部通过才得分，sum：按用例权重比例得分
+	DependsOn   string  `json:"depends_on"`                       // 依赖的子任务ID，逗号分隔，依赖未全部通过时不得分
+	SortOrder   int     `json:"sort_order" gorm:"default:0"`
+}
+
+// TableName 指定表名
+func (Subtask) TableName() string {
+	return "subtasks"
+}
+
+// Dependencies 解析依赖的子任务ID
+func (s *Subtask) Dependencies() []uint {
+	var ids []uint
+	for _, field := range strings.Split(s.DependsOn, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err == nil && id != 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
@@ -13,6 +13,11 @@ type TestCase struct {
 	IsExample  bool   `json:"is_example" gorm:"default:false"` // 是否为示例测试用例
 	IsHidden   bool   `json:"is_hidden" gorm:"default:false"`  // 是否为隐藏测试用例
 	Weight     int    `json:"weight" gorm:"default:1"`         // 测试用例权重
+	SortOrder  int    `json:"sort_order" gorm:"default:0"`     // 评测顺序，越小越先评测
+	InputFile  string `json:"input_file,omitempty"`            // 存放在文件存储中的输入，非空时忽略 Input
+	OutputFile string `json:"output_file,omitempty"`           // 存放在文件存储中的输出，非空时忽略 Output
+	Version    int    `json:"version" gorm:"default:0"`        // 所属测试数据版本，0 表示通过接口单独维护
+	SubtaskID  uint   `json:"subtask_id" gorm:"default:0"`     // 所属子任务，0 表示不属于任何子任务
 }
 
 // TableName 指定表名
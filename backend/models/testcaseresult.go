@@ -7,15 +7,16 @@ import (
 // TestCaseResult 测试用例结果实体模型
 type TestCaseResult struct {
 	gorm.Model
-	SubmissionID   uint   `json:"submission_id" gorm:"not null;index"`
-	TestCaseID     uint   `json:"test_case_id" gorm:"not null"`
+	SubmissionID   uint   `json:"submission_id" gorm:"not null;index;uniqueIndex:idx_submission_test_case"`
+	TestCaseID     uint   `json:"test_case_id" gorm:"not null;uniqueIndex:idx_submission_test_case"`
 	Input          string `json:"input" gorm:"type:text"`
 	ExpectedOutput string `json:"expected_output" gorm:"type:text"`
 	UserOutput     string `json:"user_output" gorm:"type:text"`
-	Status         string `json:"status"`   // passed, failed, time_limit_exceeded, memory_limit_exceeded, runtime_error
+	Status         string `json:"status"`   // passed, failed, time_limit_exceeded, memory_limit_exceeded, runtime_error, skipped
 	RunTime        int    `json:"run_time"` // 毫秒
 	Memory         int    `json:"memory"`   // KB
 	ErrorMessage   string `json:"error_message" gorm:"type:text"`
+	Signal         string `json:"signal,omitempty"` // 导致程序退出的信号，例如 SIGSEGV
 }
 
 // TableName 指定表名
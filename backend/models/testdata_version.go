@@ -0,0 +1,21 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// TestDataVersion 题目测试数据版本，每次上传压缩包生成一个新版本
+type TestDataVersion struct {
+	gorm.Model
+	ProblemID  uint   `json:"problem_id" gorm:"not null;uniqueIndex:idx_problem_version"`
+	Version    int    `json:"version" gorm:"not null;uniqueIndex:idx_problem_version"`
+	CaseCount  int    `json:"case_count"`
+	TotalSize  int64  `json:"total_size"` // 解压后的字节数
+	Checksum   string `json:"checksum"`   // 压缩包的 SHA-256
+	UploadedBy uint   `json:"uploaded_by"`
+}
+
+// TableName 指定表名
+func (TestDataVersion) TableName() string {
+	return "test_data_versions"
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"backend/config"
+	"github.com/IBM/sarama"
+)
+
+// kafkaQueue 基于Kafka的队列，分区与位点由Kafka管理
+type kafkaQueue struct {
+	brokers  []string
+	producer sarama.SyncProducer
+}
+
+func newKafkaQueue(cfg *config.Config) (*kafkaQueue, error) {
+	kafkaConfig := sarama.NewConfig()
+	kafkaConfig.Producer.Return.Successes = true
+	kafkaConfig.Producer.Return.Errors = true
+	kafkaConfig.ClientID = "oj-queue"
+
+	producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, kafkaConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaQueue{brokers: cfg.Kafka.Brokers, producer: producer}, nil
+}
+
+// Publish Queue接口实现
+func (q *kafkaQueue) Publish(ctx context.Context, topic, key string, value []byte) error {
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(value),
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	_, _, err := q.producer.SendMessage(msg)
+	return err
+}
+
+// Consume Queue接口实现。handler 返回错误时结束当前会话，未提交位点的消息在重新加入后再次投递
+func (q *kafkaQueue) Consume(ctx context.Context, group string, topics []string, handler Handler) error {
+	kafkaConfig := sarama.NewConfig()
+	kafkaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	consumerGroup, err := sarama.NewConsumerGroup(q.brokers, group, kafkaConfig)
+	if err != nil {
+		return err
+	}
+	// Close 会等待正在执行的 handler 完成后离开消费者组
+	defer func() {
+		if err := consumerGroup.Close(); err != nil {
+			log.Printf("Error closing consumer group %s: %v", group, err)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if err := consumerGroup.Consume(ctx, topics, kafkaHandler(handler)); err != nil {
+			log.Printf("Error consuming %v in group %s: %v", topics, group, err)
+			sleep(ctx, retryDelay)
+		}
+	}
+	return nil
+}
+
+// Driver Queue接口实现
+func (q *kafkaQueue) Driver() string {
+	return DriverKafka
+}
+
+// Close Queue接口实现
+func (q *kafkaQueue) Close() error {
+	return q.producer.Close()
+}
+
+// kafkaHandler 将 Handler 适配为 sarama.ConsumerGroupHandler
+type kafkaHandler Handler
+
+// Setup ConsumerGroupHandler接口实现
+func (h kafkaHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup ConsumerGroupHandler接口实现
+func (h kafkaHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim ConsumerGroupHandler接口实现，消息处理成功后才提交位点
+func (h kafkaHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		err := h(session.Context(), &Message{
+			Topic: message.Topic,
+			Key:   string(message.Key),
+			Value: message.Value,
+			ID:    fmt.Sprintf("%d/%d", message.Partition, message.Offset),
+		})
+		if session.Context().Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		session.MarkMessage(message, "")
+	}
+	return nil
+}
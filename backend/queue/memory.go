@@ -0,0 +1,200 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"backend/config"
+)
+
+// memoryRetention 没有消费者组的主题最多保留的消息数
+const memoryRetention = 10000
+
+// memoryQueue 进程内队列，用于单机部署与测试，API服务与内嵌判题机共享同一实例。
+// 每个分区是一段日志，各消费者组记录自己的位点，所有消费者组都处理过的消息被清理，进程退出后消息丢失
+type memoryQueue struct {
+	mu         sync.Mutex
+	partitions int
+	topics     map[string][]*memoryPartition
+	seq        uint64
+}
+
+// memoryPartition 进程内队列的一个分区
+type memoryPartition struct {
+	base     int64            // messages[0] 的位点
+	messages []*Message       // 未被所有消费者组处理的消息
+	offsets  map[string]int64 // 消费者组下一条要处理的位点
+	claimed  map[string]bool  // 消费者组是否已有消费者
+	notify   chan struct{}    // 有新消息或消费者释放分区时关闭并替换
+}
+
+var (
+	memory     *memoryQueue
+	memoryOnce sync.Once
+)
+
+// Memory 返回进程内队列，同一进程中多次调用返回同一实例
+func Memory(cfg *config.Config) Queue {
+	memoryOnce.Do(func() {
+		memory = &memoryQueue{
+			partitions: partitions(cfg),
+			topics:     make(map[string][]*memoryPartition),
+		}
+	})
+	return memory
+}
+
+// topic 返回主题的分区，不存在时创建，调用时需持有锁
+func (q *memoryQueue) topic(name string) []*memoryPartition {
+	parts, ok := q.topics[name]
+	if !ok {
+		parts = make([]*memoryPartition, q.partitions)
+		for i := range parts {
+			parts[i] = &memoryPartition{
+				offsets: make(map[string]int64),
+				claimed: make(map[string]bool),
+				notify:  make(chan struct{}),
+			}
+		}
+		q.topics[name] = parts
+	}
+	return parts
+}
+
+// Publish Queue接口实现
+func (q *memoryQueue) Publish(ctx context.Context, topic, key string, value []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	parts := q.topic(topic)
+	q.seq++
+	index := partitionOf(key, len(parts), q.seq)
+	p := parts[index]
+	p.messages = append(p.messages, &Message{
+		Topic: topic,
+		Key:   key,
+		Value: append([]byte(nil), value...),
+		ID:    fmt.Sprintf("%d/%d", index, p.base+int64(len(p.messages))),
+	})
+	p.trim()
+	p.wake()
+	return nil
+}
+
+// Consume Queue接口实现。handler 返回错误时等待一段时间后重新处理同一条消息
+func (q *memoryQueue) Consume(ctx context.Context, group string, topics []string, handler Handler) error {
+	var wg sync.WaitGroup
+	q.mu.Lock()
+	for _, topic := range topics {
+		for _, p := range q.topic(topic) {
+			wg.Add(1)
+			go func(p *memoryPartition) {
+				defer wg.Done()
+				q.consumePartition(ctx, p, group, handler)
+			}(p)
+		}
+	}
+	q.mu.Unlock()
+
+	wg.Wait()
+	return nil
+}
+
+// consumePartition 占用分区后按顺序处理消息，直到ctx取消
+func (q *memoryQueue) consumePartition(ctx context.Context, p *memoryPartition, group string, handler Handler) {
+	q.mu.Lock()
+	for p.claimed[group] {
+		notify := p.notify
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-notify:
+		}
+		q.mu.Lock()
+	}
+	p.claimed[group] = true
+	if _, ok := p.offsets[group]; !ok {
+		p.offsets[group] = p.base
+	}
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(p.claimed, group)
+		p.wake()
+		q.mu.Unlock()
+	}()
+
+	for {
+		q.mu.Lock()
+		offset := p.offsets[group]
+		if offset < p.base {
+			offset = p.base
+		}
+		if offset >= p.base+int64(len(p.messages)) {
+			notify := p.notify
+			q.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return
+			case <-notify:
+			}
+			continue
+		}
+		message := p.messages[offset-p.base]
+		q.mu.Unlock()
+
+		if err := handler(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Error handling message %s of %s in group %s, retrying: %v", message.ID, message.Topic, group, err)
+			if !sleep(ctx, retryDelay) {
+				return
+			}
+			continue
+		}
+
+		q.mu.Lock()
+		p.offsets[group] = offset + 1
+		p.trim()
+		q.mu.Unlock()
+	}
+}
+
+// trim 清理所有消费者组都已处理的消息，调用时需持有锁
+func (p *memoryPartition) trim() {
+	end := p.base + int64(len(p.messages))
+	min := end
+	for _, offset := range p.offsets {
+		if offset < min {
+			min = offset
+		}
+	}
+	if len(p.offsets) == 0 {
+		min = end - memoryRetention
+	}
+	if drop := min - p.base; drop > 0 {
+		p.messages = append([]*Message(nil), p.messages[drop:]...)
+		p.base = min
+	}
+}
+
+// wake 唤醒等待该分区的消费者，调用时需持有锁
+func (p *memoryPartition) wake() {
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// Driver Queue接口实现
+func (q *memoryQueue) Driver() string {
+	return DriverMemory
+}
+
+// Close Queue接口实现，进程内队列在进程退出前一直可用
+func (q *memoryQueue) Close() error {
+	return nil
+}
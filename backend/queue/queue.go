@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"backend/config"
+)
+
+// 队列驱动
+const (
+	DriverKafka  = "kafka"
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// Message 队列中的一条消息
+type Message struct {
+	Topic string
+	Key   string
+	Value []byte
+	ID    string // 消息在队列中的位置，仅用于日志与排查
+}
+
+// Handler 处理一条消息。返回nil后消息被确认；返回错误时消息不会被确认，之后重新投递
+type Handler func(ctx context.Context, message *Message) error
+
+// Queue 判题任务、判题结果与心跳使用的消息队列。
+// 同一主题中键相同的消息进入同一分区，同一消费者组内每个分区同时只有一个消费者，按顺序处理
+type Queue interface {
+	// Publish 发送一条消息，返回时消息已被队列保存
+	Publish(ctx context.Context, topic, key string, value []byte) error
+	// Consume 以消费者组 group 消费 topics 中的消息，每个分区在各自的协程中调用 handler，
+	// 直到ctx取消，返回前等待正在执行的 handler 完成
+	Consume(ctx context.Context, group string, topics []string, handler Handler) error
+	// Driver 队列驱动名称
+	Driver() string
+	// Close 关闭队列连接
+	Close() error
+}
+
+// retryDelay handler 返回错误或消费出错后重新消费前的等待时间
+const retryDelay = time.Second
+
+// New 按 kafka.driver 配置创建队列
+func New(cfg *config.Config) (Queue, error) {
+	switch cfg.Kafka.Driver {
+	case "", DriverKafka:
+		return newKafkaQueue(cfg)
+	case DriverRedis:
+		return newRedisQueue(cfg)
+	case DriverMemory:
+		return Memory(cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported queue driver: %s", cfg.Kafka.Driver)
+	}
+}
+
+// partitions redis 与 memory 驱动每个主题的分区数
+func partitions(cfg *config.Config) int {
+	if cfg.Kafka.Partitions <= 0 {
+		return 1
+	}
+	return cfg.Kafka.Partitions
+}
+
+// partitionOf 消息所在的分区，没有键的消息按 seq 轮流分配
+func partitionOf(key string, n int, seq uint64) int {
+	if key == "" {
+		return int(seq % uint64(n))
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// sleep 等待 d 或ctx取消，ctx取消时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
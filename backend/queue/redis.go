@@ -0,0 +1,404 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend/common/database"
+	"backend/config"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// redisBlock 等待新消息的最长时间，之后重新检查ctx
+	redisBlock = time.Second
+	// redisMaxLen 每个分区流保留的大致消息数
+	redisMaxLen = 100000
+	// redisClaimInterval 没有新消息时检查待确认消息的间隔
+	redisClaimInterval = time.Minute
+	// redisLeaseTTL 分区租约与消费者组成员身份的有效期，消费者崩溃后租约过期，分区由其他消费者接管
+	redisLeaseTTL = 30 * time.Second
+	// redisLeaseRenew 续期租约与成员身份的间隔
+	redisLeaseRenew = 10 * time.Second
+	// redisLeaseRetry 未取得租约或主动让出租约后再次争取的间隔
+	redisLeaseRetry = 5 * time.Second
+)
+
+var (
+	// redisRenewScript 租约仍由本消费者持有时续期
+	redisRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	// redisReleaseScript 租约仍由本消费者持有时释放
+	redisReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// redisQueue 基于 Redis Streams 的队列。每个分区是一个流 <topic>:<partition>，
+// 消费者组使用 XREADGROUP，处理成功后 XACK。同一消费者组内每个分区由持有其租约的一个消费者按顺序消费，
+// 各消费者持有的租约数不超过分区总数按活跃消费者数的平均值，消费者崩溃后租约过期，分区与未确认的消息由其他消费者接管
+type redisQueue struct {
+	client     *redis.Client
+	partitions int
+	consumer   string // 本进程的消费者名称
+	seq        uint64
+}
+
+func newRedisQueue(cfg *config.Config) (*redisQueue, error) {
+	client := database.GetRedisClient()
+	if client == nil {
+		var err error
+		if client, err = database.InitRedis(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return &redisQueue{
+		client:     client,
+		partitions: partitions(cfg),
+		consumer:   fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}, nil
+}
+
+// stream 分区对应的流
+func (q *redisQueue) stream(topic string, partition int) string {
+	return fmt.Sprintf("%s:%d", topic, partition)
+}
+
+// Publish Queue接口实现
+func (q *redisQueue) Publish(ctx context.Context, topic, key string, value []byte) error {
+	partition := partitionOf(key, q.partitions, atomic.AddUint64(&q.seq, 1))
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream(topic, partition),
+		MaxLen: redisMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"key": key, "value": value},
+	}).Err()
+}
+
+// Consume Queue接口实现。每个分区在取得租约后才消费，handler 返回错误时消息留在待确认列表中，稍后重新处理
+func (q *redisQueue) Consume(ctx context.Context, group string, topics []string, handler Handler) error {
+	for _, topic := range topics {
+		for i := 0; i < q.partitions; i++ {
+			err := q.client.XGroupCreateMkStream(ctx, q.stream(topic, i), group, "0").Err()
+			if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				return err
+			}
+		}
+	}
+
+	c := &redisConsumer{q: q, group: group, streams: int32(len(topics) * q.partitions)}
+	if err := c.join(ctx); err != nil {
+		return err
+	}
+	defer c.leave()
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	go c.heartbeat(heartbeatCtx)
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		for i := 0; i < q.partitions; i++ {
+			wg.Add(1)
+			go func(topic, stream string) {
+				defer wg.Done()
+				q.ownStream(ctx, c, topic, stream, handler)
+			}(topic, q.stream(topic, i))
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+// ownStream 取得分区租约后消费分区，失去或让出租约后停止消费并等待重新取得，直到ctx取消
+func (q *redisQueue) ownStream(ctx context.Context, c *redisConsumer, topic, stream string, handler Handler) {
+	key := c.leaseKey(stream)
+	for ctx.Err() == nil {
+		if !c.acquire(ctx, key) {
+			sleep(ctx, redisLeaseRetry)
+			continue
+		}
+
+		// 停止消费后继续续期，直到正在执行的 handler 完成
+		consumeCtx, stop := context.WithCancel(ctx)
+		renewCtx, stopRenew := context.WithCancel(context.Background())
+		shed := make(chan bool, 1)
+		go func() {
+			shed <- c.renew(renewCtx, key, stop)
+		}()
+		q.consumeStream(consumeCtx, topic, stream, c.group, handler)
+		stop()
+		stopRenew()
+		c.release(key, <-shed)
+		sleep(ctx, redisLeaseRetry)
+	}
+}
+
+// redisConsumer 一次 Consume 调用在消费者组中的成员身份与持有的分区租约
+type redisConsumer struct {
+	q       *redisQueue
+	group   string
+	streams int32 // 消费的分区流总数
+	held    int32 // 持有的租约数
+	members int32 // 消费者组内的活跃消费者数
+}
+
+// membersKey 消费者组成员的有序集合，分数为最近一次心跳的时间
+func (c *redisConsumer) membersKey() string {
+	return "queue:members:" + c.group
+}
+
+// leaseKey 分区流在消费者组内的租约
+func (c *redisConsumer) leaseKey(stream string) string {
+	return "queue:lease:" + c.group + ":" + stream
+}
+
+// join 登记为消费者组的活跃成员并统计成员数
+func (c *redisConsumer) join(ctx context.Context) error {
+	now := time.Now()
+	key := c.membersKey()
+	pipe := c.q.client.TxPipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(now.UnixMilli()), Member: c.q.consumer})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", now.Add(-redisLeaseTTL).UnixMilli()))
+	count := pipe.ZCard(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.members, int32(count.Val()))
+	return nil
+}
+
+// heartbeat 定期续期成员身份，直到ctx取消
+func (c *redisConsumer) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(redisLeaseRenew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := c.join(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error renewing membership of group %s: %v", c.group, err)
+		}
+	}
+}
+
+// leave 退出消费者组，其他消费者据此提高各自的租约上限
+func (c *redisConsumer) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), redisBlock)
+	defer cancel()
+	if err := c.q.client.ZRem(ctx, c.membersKey(), c.q.consumer).Err(); err != nil {
+		log.Printf("Error leaving group %s: %v", c.group, err)
+	}
+}
+
+// share 本消费者最多持有的租约数
+func (c *redisConsumer) share() int32 {
+	members := atomic.LoadInt32(&c.members)
+	if members < 1 {
+		members = 1
+	}
+	return (c.streams + members - 1) / members
+}
+
+// acquire 尝试取得分区租约，已持有的租约达到上限时不再争取
+func (c *redisConsumer) acquire(ctx context.Context, key string) bool {
+	if atomic.AddInt32(&c.held, 1) > c.share() {
+		atomic.AddInt32(&c.held, -1)
+		return false
+	}
+	ok, err := c.q.client.SetNX(ctx, key, c.q.consumer, redisLeaseTTL).Result()
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Error acquiring lease %s: %v", key, err)
+	}
+	if err != nil || !ok {
+		atomic.AddInt32(&c.held, -1)
+		return false
+	}
+	return true
+}
+
+// renew 定期续期租约，直到ctx取消。租约丢失、无法续期超过有效期或持有的租约超过上限时调用 stop 停止消费，
+// 返回是否因超过上限主动让出（此时已减少持有数）
+func (c *redisConsumer) renew(ctx context.Context, key string, stop context.CancelFunc) bool {
+	ticker := time.NewTicker(redisLeaseRenew)
+	defer ticker.Stop()
+	renewed := time.Now()
+	shed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return shed
+		case <-ticker.C:
+		}
+
+		// 有新的消费者加入时让出超过上限的租约
+		if !shed && c.shed() {
+			shed = true
+			stop()
+		}
+
+		res, err := redisRenewScript.Run(ctx, c.q.client, []string{key}, c.q.consumer, redisLeaseTTL.Milliseconds()).Int()
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return shed
+		case err != nil:
+			log.Printf("Error renewing lease %s: %v", key, err)
+			if time.Since(renewed) > redisLeaseTTL {
+				stop()
+			}
+		case res == 0:
+			log.Printf("Lease %s lost", key)
+			stop()
+		default:
+			renewed = time.Now()
+		}
+	}
+}
+
+// shed 持有的租约超过上限时减少一个持有数，返回是否需要让出
+func (c *redisConsumer) shed() bool {
+	for {
+		held := atomic.LoadInt32(&c.held)
+		if held <= c.share() {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&c.held, held, held-1) {
+			return true
+		}
+	}
+}
+
+// release 释放租约，shed 为真时持有数已在让出时减少
+func (c *redisConsumer) release(key string, shed bool) {
+	if !shed {
+		atomic.AddInt32(&c.held, -1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisBlock)
+	defer cancel()
+	if err := redisReleaseScript.Run(ctx, c.q.client, []string{key}, c.q.consumer).Err(); err != nil {
+		log.Printf("Error releasing lease %s: %v", key, err)
+	}
+}
+
+// consumeStream 在持有租约期间消费一个分区流，先处理未确认的消息（包括之前持有租约的消费者留下的），再读取新消息
+func (q *redisQueue) consumeStream(ctx context.Context, topic, stream, group string, handler Handler) {
+	pending := true
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		messages, err := q.read(ctx, stream, group, pending)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error reading %s in group %s: %v", stream, group, err)
+				sleep(ctx, retryDelay)
+			}
+			continue
+		}
+		if len(messages) == 0 {
+			if pending {
+				// 待确认的消息已处理完，开始读取新消息
+				pending = false
+				lastClaim = time.Now()
+			} else {
+				pending = time.Since(lastClaim) > redisClaimInterval
+			}
+			continue
+		}
+
+		for _, m := range messages {
+			message := &Message{Topic: topic, ID: stream + "/" + m.ID}
+			if v, ok := m.Values["key"].(string); ok {
+				message.Key = v
+			}
+			if v, ok := m.Values["value"].(string); ok {
+				message.Value = []byte(v)
+			}
+
+			if err := handler(ctx, message); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Error handling message %s in group %s, retrying: %v", message.ID, group, err)
+				pending = true
+				sleep(ctx, retryDelay)
+				break
+			}
+			if err := q.client.XAck(ctx, stream, group, m.ID).Err(); err != nil {
+				log.Printf("Error acknowledging message %s in group %s: %v", message.ID, group, err)
+			}
+		}
+	}
+}
+
+// read 读取一条消息。pending 为 true 时读取本消费者未确认的消息，没有时接管其他消费者留下的消息：
+// 分区只由租约持有者消费，其他消费者未确认的消息来自已失去租约的消费者，立即接管以保持分区内的顺序
+func (q *redisQueue) read(ctx context.Context, stream, group string, pending bool) ([]redis.XMessage, error) {
+	if pending {
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: q.consumer,
+			Streams:  []string{stream, "0"},
+			Count:    1,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if len(streams) > 0 && len(streams[0].Messages) > 0 {
+			return streams[0].Messages, nil
+		}
+
+		messages, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    group,
+			Consumer: q.consumer,
+			MinIdle:  0,
+			Start:    "0",
+			Count:    1,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		return messages, nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: q.consumer,
+		Streams:  []string{stream, ">"},
+		Count:    1,
+		Block:    redisBlock,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil || len(streams) == 0 {
+		return nil, err
+	}
+	return streams[0].Messages, nil
+}
+
+// Driver Queue接口实现
+func (q *redisQueue) Driver() string {
+	return DriverRedis
+}
+
+// Close Queue接口实现，Redis 客户端与缓存共享，由进程退出时关闭
+func (q *redisQueue) Close() error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// cgroup 单次运行使用的 cgroup v2 子目录
+type cgroup struct {
+	path string
+	fd   int
+}
+
+// cgroupStats 运行结束后从 cgroup 读取的统计信息
+type cgroupStats struct {
+	cpuTime    time.Duration
+	memoryPeak int64 // 字节
+	oomKills   int
+}
+
+// newCgroup 在委派目录下创建子 cgroup 并写入资源限制
+func newCgroup(root string, cfg *Config) (*cgroup, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	// 在委派目录上启用所需控制器，已启用时写入无副作用
+	for _, controller := range []string{"+memory", "+pids", "+cpu"} {
+		_ = os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte(controller), 0644)
+	}
+
+	path, err := os.MkdirTemp(root, "run-")
+	if err != nil {
+		return nil, err
+	}
+	cg := &cgroup{path: path, fd: -1}
+
+	limits := map[string]string{}
+	if cfg.MemoryLimit > 0 {
+		limits["memory.max"] = strconv.FormatInt(cfg.MemoryLimit, 10)
+		limits["memory.swap.max"] = "0"
+	}
+	if cfg.PidsLimit > 0 {
+		limits["pids.max"] = strconv.Itoa(cfg.PidsLimit)
+	}
+	if cfg.CPUs > 0 {
+		limits["cpu.max"] = fmt.Sprintf("%d 100000", int64(cfg.CPUs*100000))
+	}
+
+	for file, value := range limits {
+		err := os.WriteFile(filepath.Join(path, file), []byte(value), 0644)
+		// 未开启 swap 记账时不存在 memory.swap.max
+		if err != nil && !(file == "memory.swap.max" && os.IsNotExist(err)) {
+			cg.remove()
+			return nil, fmt.Errorf("write %s: %w", file, err)
+		}
+	}
+
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		cg.remove()
+		return nil, err
+	}
+	cg.fd = fd
+
+	return cg, nil
+}
+
+// stats 读取 CPU 用量、峰值内存与 OOM 次数
+func (cg *cgroup) stats() cgroupStats {
+	var stats cgroupStats
+
+	if usec, ok := readKeyedValue(filepath.Join(cg.path, "cpu.stat"), "usage_usec"); ok {
+		stats.cpuTime = time.Duration(usec) * time.Microsecond
+	}
+	if data, err := os.ReadFile(filepath.Join(cg.path, "memory.peak")); err == nil {
+		stats.memoryPeak, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	if kills, ok := readKeyedValue(filepath.Join(cg.path, "memory.events"), "oom_kill"); ok {
+		stats.oomKills = int(kills)
+	}
+
+	return stats
+}
+
+// kill 杀死 cgroup 内的所有进程
+func (cg *cgroup) kill() {
+	_ = os.WriteFile(filepath.Join(cg.path, "cgroup.kill"), []byte("1"), 0644)
+}
+
+// remove 清理 cgroup，进程退出后内核可能需要片刻才允许删除
+func (cg *cgroup) remove() {
+	if cg.fd >= 0 {
+		_ = unix.Close(cg.fd)
+		cg.fd = -1
+	}
+	cg.kill()
+	for i := 0; i < 50; i++ {
+		if err := unix.Rmdir(cg.path); err == nil || err == unix.ENOENT {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readKeyedValue 读取 "key value" 格式文件中指定键的值
+func readKeyedValue(path, key string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			value, err := strconv.ParseInt(fields[1], 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,263 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	specFD  = 3 // 接收 spec 的管道
+	errorFD = 4 // 回传初始化错误的管道
+)
+
+// devices 绑定到沙箱 /dev 下的设备文件
+var devices = []string{"null", "zero", "random", "urandom"}
+
+// Init 若当前进程是沙箱初始化进程，则完成沙箱内部的初始化并执行目标程序，
+// 不会返回；否则立即返回。必须在 main 函数开头调用。
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != initArg {
+		return
+	}
+
+	runtime.LockOSThread()
+	unix.CloseOnExec(errorFD)
+
+	err := initSandbox()
+	errPipe := os.NewFile(errorFD, "sandbox-error")
+	fmt.Fprint(errPipe, err.Error())
+	os.Exit(127)
+}
+
+// initSandbox 在新命名空间中构建根文件系统、设置限制并执行目标程序，仅在失败时返回
+func initSandbox() error {
+	specFile := os.NewFile(specFD, "sandbox-spec")
+	var s spec
+	if err := json.NewDecoder(specFile).Decode(&s); err != nil {
+		return fmt.Errorf("read spec: %w", err)
+	}
+	specFile.Close()
+
+	// 先切换到沙箱用户，未映射的身份无法在新命名空间中创建文件
+	if err := switchUser(&s); err != nil {
+		return fmt.Errorf("switch user: %w", err)
+	}
+	if err := setupRootfs(&s); err != nil {
+		return fmt.Errorf("setup rootfs: %w", err)
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("set hostname: %w", err)
+	}
+	if err := setRlimits(&s); err != nil {
+		return fmt.Errorf("set rlimits: %w", err)
+	}
+	if err := dropCapabilities(); err != nil {
+		return fmt.Errorf("drop capabilities: %w", err)
+	}
+
+	path, err := lookPath(s.Args[0], s.Env)
+	if err != nil {
+		return err
+	}
+
+	if err := installSeccomp(); err != nil {
+		return fmt.Errorf("install seccomp: %w", err)
+	}
+
+	return unix.Exec(path, s.Args, s.Env)
+}
+
+// setupRootfs 构建只读根文件系统并切换根目录到其中
+func setupRootfs(s *spec) error {
+	// 阻止挂载事件传播回宿主机
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", s.Root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=16m,mode=755"); err != nil {
+		return err
+	}
+
+	for _, path := range s.ReadOnlyPaths {
+		if err := bindMount(path, filepath.Join(s.Root, path), true); err != nil {
+			return fmt.Errorf("mount %s: %w", path, err)
+		}
+	}
+
+	if err := bindMount(s.Dir, filepath.Join(s.Root, "box"), !s.Writable); err != nil {
+		return fmt.Errorf("mount box: %w", err)
+	}
+	for _, m := range s.Mounts {
+		if err := bindMount(m.Source, filepath.Join(s.Root, m.Target), !m.Writable); err != nil {
+			return fmt.Errorf("mount %s: %w", m.Target, err)
+		}
+	}
+
+	tmp := filepath.Join(s.Root, "tmp")
+	if err := os.Mkdir(tmp, 0777); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", tmp, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=64m,mode=1777"); err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := bindMount("/dev/"+device, filepath.Join(s.Root, "dev", device), false); err != nil {
+			return fmt.Errorf("mount device %s: %w", device, err)
+		}
+	}
+
+	// 部分容器环境禁止挂载新的 proc，此时沙箱内没有 /proc
+	proc := filepath.Join(s.Root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return err
+	}
+	_ = unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	if err := unix.Mount("", s.Root, "", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return err
+	}
+
+	// 切换根目录并卸载旧的根
+	if err := unix.Chdir(s.Root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return err
+	}
+	return unix.Chdir("/box")
+}
+
+// bindMount 将宿主机路径绑定挂载到沙箱内，不存在的路径直接跳过
+func bindMount(src, dst string, readonly bool) error {
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	// 符号链接（例如合并 /usr 后的 /bin）原样复制
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	if info.IsDir() {
+		err = os.MkdirAll(dst, 0755)
+	} else {
+		var f *os.File
+		f, err = os.Create(dst)
+		if f != nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount(src, dst, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+
+	// 只读重新挂载时必须保留源挂载点上被锁定的标志
+	var stat unix.Statfs_t
+	if err := unix.Statfs(src, &stat); err != nil {
+		return err
+	}
+	locked := uintptr(stat.Flags) & (unix.ST_NOSUID | unix.ST_NODEV | unix.ST_NOEXEC |
+		unix.ST_NOATIME | unix.ST_NODIRATIME | unix.ST_RELATIME)
+	flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | locked
+	if readonly {
+		flags |= unix.MS_RDONLY
+	}
+	return unix.Mount("", dst, "", flags, "")
+}
+
+// setRlimits 设置进程级资源限制，作为 cgroup 之外的补充
+func setRlimits(s *spec) error {
+	limits := map[int]uint64{
+		unix.RLIMIT_CPU:  s.CPUSeconds,
+		unix.RLIMIT_CORE: 0,
+	}
+	if s.StackLimit > 0 {
+		limits[unix.RLIMIT_STACK] = s.StackLimit
+	}
+	if s.FileLimit > 0 {
+		limits[unix.RLIMIT_FSIZE] = s.FileLimit
+	}
+	if s.ProcLimit > 0 {
+		limits[unix.RLIMIT_NPROC] = s.ProcLimit
+	}
+
+	for resource, value := range limits {
+		rlimit := unix.Rlimit{Cur: value, Max: value}
+		// CPU 超限先收到 SIGXCPU，硬限制再多留一秒后 SIGKILL
+		if resource == unix.RLIMIT_CPU {
+			rlimit.Max = value + 1
+		}
+		if err := unix.Setrlimit(resource, &rlimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// switchUser 切换到沙箱用户，此时仍保留命名空间内的能力用于挂载
+func switchUser(s *spec) error {
+	if s.DropGroups {
+		if err := syscall.Setgroups(nil); err != nil {
+			return err
+		}
+	}
+	if err := syscall.Setgid(s.GID); err != nil {
+		return err
+	}
+	return syscall.Setuid(s.UID)
+}
+
+// dropCapabilities 清除环境能力并禁止再获得特权，目标程序执行后不再拥有任何能力
+func dropCapabilities() error {
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return err
+	}
+	return unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+}
+
+// lookPath 在沙箱的 PATH 中查找可执行文件
+func lookPath(name string, env []string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+
+	for _, kv := range env {
+		value, ok := strings.CutPrefix(kv, "PATH=")
+		if !ok {
+			continue
+		}
+		for _, dir := range filepath.SplitList(value) {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("executable %q not found", name)
+}
@@ -0,0 +1,81 @@
+// Package sandbox 在隔离环境中运行不受信任的用户程序。
+//
+// 程序运行在全新的 user/mount/pid/net/ipc/uts 命名空间中，根文件系统只包含
+// 只读挂载的系统目录、可选可写的工作目录 /box 以及私有的 /tmp，宿主机上的其他
+// 文件（例如 config.json）不可见。资源通过 cgroup v2 限制内存、CPU 与进程数，
+// 并通过 seccomp 白名单限制可用的系统调用。
+//
+// 使用沙箱的程序必须在 main 函数开头调用 Init，沙箱通过重新执行当前程序完成
+// 子进程内部的初始化。
+package sandbox
+
+import (
+	"io"
+	"time"
+)
+
+// initArg 沙箱初始化进程的 argv[0] 标记
+const initArg = "oj-sandbox-init"
+
+// Config 沙箱运行参数
+type Config struct {
+	Args     []string // 在沙箱内执行的命令，相对路径以 /box 为基准
+	Env      []string // 环境变量
+	Dir      string   // 宿主机工作目录，挂载为沙箱内的 /box
+	Writable bool     // /box 是否可写，编译阶段需要
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	OnStart func() // 进程启动后调用，例如关闭父进程持有的管道端
+
+	TimeLimit   time.Duration // CPU 时间限制
+	WallLimit   time.Duration // 墙钟时间限制，为 0 时取 CPU 时间限制的两倍加一秒
+	MemoryLimit int64         // 内存限制，字节
+	PidsLimit   int           // 进程（线程）数限制
+	CPUs        float64       // 可用 CPU 核数，为 0 时不限制
+	OutputLimit int64         // 单个文件写入大小限制，字节
+
+	UID           int      // 沙箱内运行的用户ID，仅以 root 运行时生效
+	GID           int      // 沙箱内运行的用户组ID，仅以 root 运行时生效
+	CgroupRoot    string   // 委派给判题机的 cgroup v2 目录，为空时仅使用 rlimit
+	ReadOnlyPaths []string // 只读挂载到沙箱内的宿主机路径
+	Mounts        []Mount  // 额外挂载的目录，例如编译缓存
+}
+
+// Mount 额外的绑定挂载
+type Mount struct {
+	Source   string `json:"source"`   // 宿主机路径
+	Target   string `json:"target"`   // 沙箱内路径
+	Writable bool   `json:"writable"` // 是否可写
+}
+
+// Result 沙箱运行结果
+type Result struct {
+	ExitCode  int
+	Signal    string        // 导致进程退出的信号名称，例如 SIGSEGV
+	WallTime  time.Duration // 墙钟时间
+	CPUTime   time.Duration // 用户态与内核态 CPU 时间之和
+	Memory    int64         // 峰值内存，KB
+	TimedOut  bool          // 超过墙钟时间被杀死
+	OOMKilled bool          // 超过内存限制被杀死
+}
+
+// spec 传递给沙箱初始化进程的参数
+type spec struct {
+	Args          []string `json:"args"`
+	Env           []string `json:"env"`
+	Dir           string   `json:"dir"`
+	Root          string   `json:"root"`
+	Writable      bool     `json:"writable"`
+	ReadOnlyPaths []string `json:"read_only_paths"`
+	Mounts        []Mount  `json:"mounts"`
+	UID           int      `json:"uid"`
+	GID           int      `json:"gid"`
+	DropGroups    bool     `json:"drop_groups"`
+	CPUSeconds    uint64   `json:"cpu_seconds"`
+	StackLimit    uint64   `json:"stack_limit"`
+	FileLimit     uint64   `json:"file_limit"`
+	ProcLimit     uint64   `json:"proc_limit"`
+}
@@ -0,0 +1,190 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// initCaps 初始化进程在新命名空间内完成挂载与降权所需的能力
+var initCaps = []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_SETUID, unix.CAP_SETGID}
+
+// Run 在沙箱中运行程序并等待其结束
+func Run(cfg *Config) (*Result, error) {
+	if len(cfg.Args) == 0 {
+		return nil, fmt.Errorf("sandbox: empty command")
+	}
+
+	wallLimit := cfg.WallLimit
+	if wallLimit == 0 {
+		wallLimit = 2*cfg.TimeLimit + time.Second
+	}
+
+	// 以 root 运行时切换到专用的低权限用户，否则沿用当前用户
+	uid, gid := os.Getuid(), os.Getgid()
+	privileged := uid == 0
+	if privileged {
+		uid, gid = cfg.UID, cfg.GID
+	}
+	if err := prepareDir(cfg.Dir, cfg.Writable, privileged, uid, gid); err != nil {
+		return nil, fmt.Errorf("sandbox: prepare dir: %w", err)
+	}
+
+	// 新根文件系统的挂载点
+	root, err := os.MkdirTemp("", "oj-sandbox-root-")
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: create root: %w", err)
+	}
+	defer os.Remove(root)
+	if err := os.Chmod(root, 0755); err != nil {
+		return nil, fmt.Errorf("sandbox: create root: %w", err)
+	}
+
+	s := spec{
+		Args:          cfg.Args,
+		Env:           cfg.Env,
+		Dir:           cfg.Dir,
+		Root:          root,
+		Writable:      cfg.Writable,
+		ReadOnlyPaths: cfg.ReadOnlyPaths,
+		Mounts:        cfg.Mounts,
+		UID:           uid,
+		GID:           gid,
+		DropGroups:    privileged,
+		CPUSeconds:    uint64(math.Ceil(cfg.TimeLimit.Seconds())) + 1,
+		StackLimit:    uint64(cfg.MemoryLimit),
+		FileLimit:     uint64(cfg.OutputLimit),
+	}
+
+	sysProcAttr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}},
+		GidMappingsEnableSetgroups: privileged,
+		AmbientCaps:                initCaps,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	// 优先使用 cgroup v2 限制资源，不可用时退化为 rlimit
+	var cg *cgroup
+	if cfg.CgroupRoot != "" {
+		cg, err = newCgroup(cfg.CgroupRoot, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: create cgroup: %w", err)
+		}
+		defer cg.remove()
+		sysProcAttr.UseCgroupFD = true
+		sysProcAttr.CgroupFD = cg.fd
+	} else if cfg.PidsLimit > 0 {
+		s.ProcLimit = uint64(cfg.PidsLimit)
+	}
+
+	specR, specW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer specW.Close()
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		specR.Close()
+		return nil, err
+	}
+	defer errR.Close()
+
+	cmd := &exec.Cmd{
+		Path:        "/proc/self/exe",
+		Args:        []string{initArg},
+		Env:         []string{},
+		Stdin:       cfg.Stdin,
+		Stdout:      cfg.Stdout,
+		Stderr:      cfg.Stderr,
+		ExtraFiles:  []*os.File{specR, errW},
+		SysProcAttr: sysProcAttr,
+	}
+
+	err = cmd.Start()
+	specR.Close()
+	errW.Close()
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: start: %w", err)
+	}
+	start := time.Now()
+	if cfg.OnStart != nil {
+		cfg.OnStart()
+	}
+
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(wallLimit, func() {
+		timedOut.Store(true)
+		_ = cmd.Process.Kill()
+		if cg != nil {
+			cg.kill()
+		}
+	})
+	defer timer.Stop()
+
+	if err := json.NewEncoder(specW).Encode(&s); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("sandbox: send spec: %w", err)
+	}
+	specW.Close()
+
+	// 初始化进程成功执行目标程序后错误管道随 exec 关闭
+	initErr, _ := io.ReadAll(errR)
+
+	// 输出写入失败（例如超出输出限制）由调用方通过自己的 Writer 判断
+	_ = cmd.Wait()
+	wallTime := time.Since(start)
+
+	if len(initErr) > 0 {
+		return nil, fmt.Errorf("sandbox: init: %s", initErr)
+	}
+
+	state := cmd.ProcessState
+	result := &Result{
+		ExitCode: state.ExitCode(),
+		WallTime: wallTime,
+		TimedOut: timedOut.Load(),
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		result.Signal = unix.SignalName(status.Signal())
+	}
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		result.CPUTime = time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+		result.Memory = int64(usage.Maxrss)
+	}
+
+	if cg != nil {
+		stats := cg.stats()
+		if stats.cpuTime > 0 {
+			result.CPUTime = stats.cpuTime
+		}
+		if stats.memoryPeak > 0 {
+			result.Memory = stats.memoryPeak / 1024
+		}
+		result.OOMKilled = stats.oomKills > 0
+	}
+
+	return result, nil
+}
+
+// prepareDir 调整工作目录权限，使沙箱用户可以访问
+func prepareDir(dir string, writable, privileged bool, uid, gid int) error {
+	if err := os.Chmod(dir, 0755); err != nil {
+		return err
+	}
+	if writable && privileged {
+		return os.Chown(dir, uid, gid)
+	}
+	return nil
+}
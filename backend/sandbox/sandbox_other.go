@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sandbox
+
+import "errors"
+
+// Init 非 Linux 平台无需初始化
+func Init() {}
+
+// Run 沙箱依赖 Linux 命名空间与 cgroup，其他平台不可用
+func Run(cfg *Config) (*Result, error) {
+	return nil, errors.New("sandbox: only supported on linux")
+}
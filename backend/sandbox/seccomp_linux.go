@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccomp_data 中各字段的偏移
+const (
+	offsetNr   = 0
+	offsetArch = 4
+)
+
+// installSeccomp 安装系统调用白名单过滤器，白名单以外的调用返回 EPERM
+func installSeccomp() error {
+	if len(allowedSyscalls) == 0 {
+		return nil
+	}
+
+	filter, err := buildFilter(auditArch, allowedSyscalls)
+	if err != nil {
+		return err
+	}
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// buildFilter 生成 BPF 程序：架构不符时杀死进程，命中白名单时放行，其余返回 EPERM
+func buildFilter(arch uint32, syscalls []uintptr) ([]unix.SockFilter, error) {
+	// 跳转偏移只有 8 位
+	if len(syscalls) > 255 {
+		return nil, fmt.Errorf("too many syscalls in allowlist: %d", len(syscalls))
+	}
+
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetNr),
+	}
+	for i, nr := range syscalls {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), uint8(len(syscalls)-i), 0))
+	}
+	filter = append(filter,
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM)),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+	)
+
+	return filter, nil
+}
+
+func stmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
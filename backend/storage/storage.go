@@ -0,0 +1,43 @@
+// Package storage 提供测试数据等大文件的存储，存储后端可插拔。
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"backend/config"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: file not found")
+
+// FileStore 文件存储接口，key 使用 / 分隔的相对路径
+type FileStore interface {
+	// Put 写入文件，已存在时覆盖，返回写入的字节数
+	Put(key string, r io.Reader) (int64, error)
+	// Open 打开文件读取
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除文件或以 key 为前缀的目录
+	Delete(key string) error
+}
+
+// New 根据配置创建文件存储
+func New(cfg *config.Config) (FileStore, error) {
+	switch cfg.Storage.Driver {
+	case "", "local":
+		return NewLocalStore(cfg.Storage.LocalDir)
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.Storage.Driver)
+	}
+}
+
+// ReadAll 读取整个文件
+func ReadAll(store FileStore, key string) ([]byte, error) {
+	r, err := store.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
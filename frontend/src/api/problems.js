@@ -48,6 +48,21 @@ const problemsApi = {
     return apiClient.get(`/problems/${id}`).then(response => response.data)
   },
 
+  // 获取可提交的语言列表
+  getLanguages() {
+    return apiClient.get('/languages').then(response => response.data)
+  },
+
+  // 以自定义输入运行代码，不创建提交记录
+  runCode(runData) {
+    return apiClient.post('/run', runData, { timeout: 15000 }).then(response => response.data)
+  },
+
+  // 获取运行结果
+  getRun(id) {
+    return apiClient.get(`/run/${id}`).then(response => response.data)
+  },
+
   // 提交代码到指定接口
   submitCode(submissionData) {
     return apiClient.post('/submit', submissionData).then(response => response.data)
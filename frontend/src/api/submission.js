@@ -56,6 +56,46 @@ const submissionApi = {
   // 获取提交结果
   getSubmissionResult(id) {
     return apiClient.get(`/submissions/${id}/result`).then(response => response.data)
+  },
+
+  // 获取编译输出、诊断信息与运行错误的信号
+  getCompileLog(id) {
+    return apiClient.get(`/submissions/${id}/compile-log`).then(response => response.data)
+  },
+
+  // 订阅提交状态（Server-Sent Events），每收到一个状态事件调用 onEvent，返回用于取消订阅的函数
+  streamSubmission(id, onEvent) {
+    const controller = new AbortController()
+    const token = localStorage.getItem('token')
+
+    fetch(`${API_URL}/submissions/${id}/stream`, {
+      headers: token ? { 'Authorization': `Bearer ${token}` } : {},
+      signal: controller.signal
+    }).then(async response => {
+      if (!response.ok || !response.body) return
+      const reader = response.body.getReader()
+      const decoder = new TextDecoder()
+      let buffer = ''
+      for (;;) {
+        const { done, value } = await reader.read()
+        if (done) break
+        buffer += decoder.decode(value, { stream: true })
+        let index
+        while ((index = buffer.indexOf('\n\n')) >= 0) {
+          const chunk = buffer.slice(0, index)
+          buffer = buffer.slice(index + 2)
+          const data = chunk.split('\n')
+            .filter(line => line.startsWith('data:'))
+            .map(line => line.slice(5))
+            .join('\n')
+          if (data) onEvent(JSON.parse(data))
+        }
+      }
+    }).catch(error => {
+      if (error.name !== 'AbortError') console.error('订阅提交状态失败:', error)
+    })
+
+    return () => controller.abort()
   }
 }
 